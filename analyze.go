@@ -0,0 +1,370 @@
+package eredis
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/server/egovernor"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultAnalyzeTopN        = 50
+	defaultAnalyzeTopPrefixes = 100
+	defaultAnalyzeDelimiter   = ":"
+	defaultAnalyzeSamples     = 5
+)
+
+// components 组件名称到 Component 的映射，用于 egovernor 接口
+var components = sync.Map{}
+
+func init() {
+	// 遍历整个 keyspace 开销较大，必须通过 name 指定组件，默认每页之间间隔 10ms
+	egovernor.HandleFunc("/debug/redis/keyspace", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		val, ok := components.Load(query.Get("name"))
+		if !ok {
+			http.Error(w, "component not found", http.StatusNotFound)
+			return
+		}
+		opts := &AnalyzeOptions{
+			Pattern:   query.Get("pattern"),
+			Delimiter: query.Get("delimiter"),
+			Interval:  10 * time.Millisecond,
+		}
+		if v, err := time.ParseDuration(query.Get("interval")); err == nil {
+			opts.Interval = v
+		}
+		opts.Count, _ = strconv.ParseInt(query.Get("count"), 10, 64)
+		opts.Limit, _ = strconv.ParseInt(query.Get("limit"), 10, 64)
+		opts.TopN, _ = strconv.Atoi(query.Get("topN"))
+		opts.PrefixDepth, _ = strconv.Atoi(query.Get("prefixDepth"))
+
+		report, err := val.(*Component).AnalyzeKeyspace(r.Context(), opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			elog.Error("encode keyspace report fail", elog.FieldErr(err))
+		}
+	})
+}
+
+// AnalyzeOptions AnalyzeKeyspace 的选项
+type AnalyzeOptions struct {
+	Pattern       string        // Pattern 只分析匹配的 key，默认 *
+	Count         int64         // Count 每次 SCAN 的 COUNT 提示，也是每批分析的 key 数量，默认 100
+	Interval      time.Duration // Interval 两次 SCAN 之间的最小间隔，用于限速，默认不限速
+	Limit         int64         // Limit 最多分析的 key 数量，默认不限制
+	TopN          int           // TopN 报告中最大 key 的数量，默认 50
+	TopPrefixes   int           // TopPrefixes 报告中按内存排序的前缀数量，默认 100
+	Delimiter     string        // Delimiter key 前缀的分隔符，默认 ":"
+	PrefixDepth   int           // PrefixDepth 前缀包含的分段数量，默认 1，例如 user:1:name 的前缀为 user:
+	MemorySamples int           // MemorySamples MEMORY USAGE 的 SAMPLES 参数，默认 5
+}
+
+func (o *AnalyzeOptions) withDefaults() *AnalyzeOptions {
+	opts := AnalyzeOptions{}
+	if o != nil {
+		opts = *o
+	}
+	if opts.Count <= 0 {
+		opts.Count = defaultScanCount
+	}
+	if opts.TopN <= 0 {
+		opts.TopN = defaultAnalyzeTopN
+	}
+	if opts.TopPrefixes <= 0 {
+		opts.TopPrefixes = defaultAnalyzeTopPrefixes
+	}
+	if opts.Delimiter == "" {
+		opts.Delimiter = defaultAnalyzeDelimiter
+	}
+	if opts.PrefixDepth <= 0 {
+		opts.PrefixDepth = 1
+	}
+	if opts.MemorySamples <= 0 {
+		opts.MemorySamples = defaultAnalyzeSamples
+	}
+	return &opts
+}
+
+// KeyspaceReport keyspace 分析报告，内存单位为字节
+type KeyspaceReport struct {
+	Nodes       []string                 `json:"nodes"`
+	Keys        int64                    `json:"keys"`
+	Memory      int64                    `json:"memory"`
+	NoTTLKeys   int64                    `json:"noTTLKeys"`
+	NoTTLRatio  float64                  `json:"noTTLRatio"`
+	Types       map[string]*KeyTypeStats `json:"types"`
+	Prefixes    []*KeyPrefixStats        `json:"prefixes"`
+	BiggestKeys []*BigKey                `json:"biggestKeys"`
+	Truncated   bool                     `json:"truncated"` // Truncated 达到 Limit 之后提前结束
+	Duration    string                   `json:"duration"`
+}
+
+// KeyTypeStats 按类型统计的 key 数量和内存
+type KeyTypeStats struct {
+	Keys   int64 `json:"keys"`
+	Memory int64 `json:"memory"`
+}
+
+// KeyPrefixStats 按前缀统计的 key 数量和内存
+type KeyPrefixStats struct {
+	Prefix    string `json:"prefix"`
+	Keys      int64  `json:"keys"`
+	Memory    int64  `json:"memory"`
+	NoTTLKeys int64  `json:"noTTLKeys"`
+}
+
+// BigKey 占用内存最大的 key，Length 为元素数量，string 为字节数
+type BigKey struct {
+	Key    string `json:"key"`
+	Node   string `json:"node"`
+	Type   string `json:"type"`
+	Memory int64  `json:"memory"`
+	Length int64  `json:"length"`
+	TTL    int64  `json:"ttl"` // TTL 剩余过期时间，单位毫秒，-1 表示没有过期时间
+}
+
+// keySample 单个 key 的分析结果
+type keySample struct {
+	key    string
+	node   string
+	typ    string
+	memory int64
+	ttl    time.Duration
+}
+
+// keyspaceAnalyzer 汇总 key 的分析结果
+type keyspaceAnalyzer struct {
+	opts     *AnalyzeOptions
+	report   *KeyspaceReport
+	prefixes map[string]*KeyPrefixStats
+	biggest  []*BigKey
+}
+
+func newKeyspaceAnalyzer(opts *AnalyzeOptions) *keyspaceAnalyzer {
+	return &keyspaceAnalyzer{
+		opts:     opts,
+		report:   &KeyspaceReport{Types: make(map[string]*KeyTypeStats)},
+		prefixes: make(map[string]*KeyPrefixStats),
+	}
+}
+
+// add 汇总一个 key
+func (a *keyspaceAnalyzer) add(s keySample) {
+	noTTL := s.ttl < 0
+	a.report.Keys++
+	a.report.Memory += s.memory
+	if noTTL {
+		a.report.NoTTLKeys++
+	}
+
+	typ := a.report.Types[s.typ]
+	if typ == nil {
+		typ = &KeyTypeStats{}
+		a.report.Types[s.typ] = typ
+	}
+	typ.Keys++
+	typ.Memory += s.memory
+
+	prefix := a.prefix(s.key)
+	ps := a.prefixes[prefix]
+	if ps == nil {
+		ps = &KeyPrefixStats{Prefix: prefix}
+		a.prefixes[prefix] = ps
+	}
+	ps.Keys++
+	ps.Memory += s.memory
+	if noTTL {
+		ps.NoTTLKeys++
+	}
+
+	ttl := int64(-1)
+	if !noTTL {
+		ttl = s.ttl.Milliseconds()
+	}
+	a.biggest = append(a.biggest, &BigKey{Key: s.key, Node: s.node, Type: s.typ, Memory: s.memory, TTL: ttl})
+	// 超过 2 倍 TopN 时再截断，避免每次都排序
+	if len(a.biggest) >= 2*a.opts.TopN {
+		a.truncateBiggest()
+	}
+}
+
+// prefix 返回 key 的前 PrefixDepth 段，不足时返回空
+func (a *keyspaceAnalyzer) prefix(key string) string {
+	end := 0
+	for i := 0; i < a.opts.PrefixDepth; i++ {
+		idx := strings.Index(key[end:], a.opts.Delimiter)
+		if idx < 0 {
+			return ""
+		}
+		end += idx + len(a.opts.Delimiter)
+	}
+	return key[:end]
+}
+
+func (a *keyspaceAnalyzer) truncateBiggest() {
+	sort.Slice(a.biggest, func(i, j int) bool { return a.biggest[i].Memory > a.biggest[j].Memory })
+	if len(a.biggest) > a.opts.TopN {
+		a.biggest = a.biggest[:a.opts.TopN]
+	}
+}
+
+// finish 生成报告
+func (a *keyspaceAnalyzer) finish() *KeyspaceReport {
+	a.truncateBiggest()
+	a.report.BiggestKeys = a.biggest
+	if a.report.Keys > 0 {
+		a.report.NoTTLRatio = float64(a.report.NoTTLKeys) / float64(a.report.Keys)
+	}
+	prefixes := make([]*KeyPrefixStats, 0, len(a.prefixes))
+	for _, ps := range a.prefixes {
+		prefixes = append(prefixes, ps)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return prefixes[i].Memory > prefixes[j].Memory })
+	if len(prefixes) > a.opts.TopPrefixes {
+		prefixes = prefixes[:a.opts.TopPrefixes]
+	}
+	a.report.Prefixes = prefixes
+	return a.report
+}
+
+// lengthCommands key 类型对应的长度命令
+var lengthCommands = map[string]string{
+	"string": "strlen",
+	"hash":   "hlen",
+	"list":   "llen",
+	"set":    "scard",
+	"zset":   "zcard",
+	"stream": "xlen",
+}
+
+// AnalyzeKeyspace 通过 SCAN 遍历所有 master 节点，使用 TYPE、MEMORY USAGE、PTTL 分析每个 key，
+// 报告占用内存最大的 key（以及元素数量）、按前缀统计的内存、按类型统计的 key 数量和没有过期时间的 key 占比。
+// 每批 key 通过 pipeline 查询，通过 Interval 限速，建议在低峰期执行。
+func (r *Component) AnalyzeKeyspace(ctx context.Context, opts *AnalyzeOptions) (*KeyspaceReport, error) {
+	start := time.Now()
+	opts = opts.withDefaults()
+	it := r.ScanKeys(ctx, opts.Pattern, &ScanOptions{Count: opts.Count, Interval: opts.Interval})
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	analyzer := newKeyspaceAnalyzer(opts)
+	nodes := make(map[string]*redis.Client, len(it.nodes))
+	for _, node := range it.nodes {
+		addr := node.Options().Addr
+		nodes[addr] = node
+		analyzer.report.Nodes = append(analyzer.report.Nodes, addr)
+	}
+
+	var (
+		batch []string
+		node  string
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		samples, err := r.sampleKeys(ctx, nodes[node], node, batch, opts.MemorySamples)
+		if err != nil {
+			return err
+		}
+		for _, s := range samples {
+			analyzer.add(s)
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	var scanned int64
+	for it.Next(ctx) {
+		if it.Node() != node {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			node = it.Node()
+		}
+		if opts.Limit > 0 && scanned >= opts.Limit {
+			analyzer.report.Truncated = true
+			break
+		}
+		scanned++
+		batch = append(batch, it.Key())
+		if int64(len(batch)) >= opts.Count {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	report := analyzer.finish()
+	if err := r.fillLength(ctx, nodes, report.BiggestKeys); err != nil {
+		return nil, err
+	}
+	report.Duration = time.Since(start).String()
+	return report, nil
+}
+
+// sampleKeys 通过 pipeline 查询一批 key 的类型、内存和过期时间，已经被删除的 key 会被跳过
+func (r *Component) sampleKeys(ctx context.Context, client *redis.Client, node string, keys []string, samples int) ([]keySample, error) {
+	// cluster 模式下节点客户端没有拦截器，统一在这里处理前缀
+	ctx = withoutKeyPrefix(ctx)
+	types := make([]*redis.StatusCmd, len(keys))
+	memories := make([]*redis.IntCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			key = r.config.KeyPrefix + key
+			types[i] = pipe.Type(ctx, key)
+			memories[i] = pipe.MemoryUsage(ctx, key, samples)
+			ttls[i] = pipe.PTTL(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && !IsNil(err) {
+		return nil, r.wrapErr("memory", err)
+	}
+
+	ret := make([]keySample, 0, len(keys))
+	for i, key := range keys {
+		typ := types[i].Val()
+		if typ == "" || typ == "none" || memories[i].Err() != nil {
+			continue
+		}
+		ret = append(ret, keySample{key: key, node: node, typ: typ, memory: memories[i].Val(), ttl: ttls[i].Val()})
+	}
+	return ret, nil
+}
+
+// fillLength 查询最大 key 的元素数量
+func (r *Component) fillLength(ctx context.Context, nodes map[string]*redis.Client, keys []*BigKey) error {
+	ctx = withoutKeyPrefix(ctx)
+	for _, bk := range keys {
+		name, ok := lengthCommands[bk.Type]
+		if !ok {
+			continue
+		}
+		cmd := redis.NewIntCmd(ctx, name, r.config.KeyPrefix+bk.Key)
+		if err := nodes[bk.Node].Process(ctx, cmd); err != nil && !IsNil(err) {
+			return r.wrapErr(name, err)
+		}
+		bk.Length = cmd.Val()
+	}
+	return nil
+}
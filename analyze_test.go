@@ -0,0 +1,37 @@
+package eredis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyspaceAnalyzer(t *testing.T) {
+	a := newKeyspaceAnalyzer((&AnalyzeOptions{TopN: 2}).withDefaults())
+	assert.Equal(t, "user:", a.prefix("user:1:name"))
+	assert.Equal(t, "", a.prefix("counter"))
+
+	a.add(keySample{key: "user:1", node: "n1", typ: "hash", memory: 100, ttl: -1})
+	a.add(keySample{key: "user:2", node: "n1", typ: "hash", memory: 300, ttl: time.Minute})
+	a.add(keySample{key: "feed:1", node: "n2", typ: "list", memory: 1000, ttl: -1})
+	a.add(keySample{key: "feed:2", node: "n2", typ: "list", memory: 10, ttl: time.Second})
+	a.add(keySample{key: "counter", node: "n2", typ: "string", memory: 50, ttl: -1})
+	report := a.finish()
+
+	assert.Equal(t, int64(5), report.Keys)
+	assert.Equal(t, int64(1460), report.Memory)
+	assert.Equal(t, int64(3), report.NoTTLKeys)
+	assert.InDelta(t, 0.6, report.NoTTLRatio, 0.0001)
+	assert.Equal(t, &KeyTypeStats{Keys: 2, Memory: 1010}, report.Types["list"])
+
+	assert.Len(t, report.BiggestKeys, 2)
+	assert.Equal(t, &BigKey{Key: "feed:1", Node: "n2", Type: "list", Memory: 1000, TTL: -1}, report.BiggestKeys[0])
+	assert.Equal(t, int64(60000), report.BiggestKeys[1].TTL)
+
+	assert.Equal(t, []*KeyPrefixStats{
+		{Prefix: "feed:", Keys: 2, Memory: 1010, NoTTLKeys: 1},
+		{Prefix: "user:", Keys: 2, Memory: 400, NoTTLKeys: 1},
+		{Prefix: "", Keys: 1, Memory: 50, NoTTLKeys: 1},
+	}, report.Prefixes)
+}
@@ -0,0 +1,297 @@
+package eredis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/gotomicro/ego/core/util/xtime"
+	"github.com/redis/go-redis/v9"
+)
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+const breakerBuckets = 10
+
+var breakerStateGauge = emetric.GaugeVecOpts{
+	Namespace: emetric.DefaultNamespace,
+	Name:      "client_redis_breaker_state",
+	Help:      "redis circuit breaker state, 0 closed, 1 open, 2 half open",
+	Labels:    []string{"name", "node"},
+}.Build()
+
+var breakerRejectCounter = emetric.CounterVecOpts{
+	Namespace: emetric.DefaultNamespace,
+	Name:      "client_redis_breaker_reject_total",
+	Help:      "redis commands rejected by circuit breaker",
+	Labels:    []string{"name", "node"},
+}.Build()
+
+// BreakerConfig 熔断配置，在统计窗口内错误率或慢调用比例超过阈值时熔断，熔断期间命令直接返回 ErrCircuitOpen
+type BreakerConfig struct {
+	PerNode           bool          // PerNode cluster 模式下按节点熔断，默认整个组件共用一个熔断器
+	Window            time.Duration // Window 统计窗口，默认 10s
+	MinRequests       int64         // MinRequests 窗口内请求数达到该值才会触发熔断，默认 20
+	ErrorRatio        float64       // ErrorRatio 错误率阈值，默认 0.5，只统计超时、连接、连接池等基础设施类错误
+	SlowCallThreshold time.Duration // SlowCallThreshold 慢调用门限值，默认 500ms
+	SlowCallRatio     float64       // SlowCallRatio 慢调用比例阈值，默认 0 表示不按慢调用熔断
+	OpenTimeout       time.Duration // OpenTimeout 熔断持续时间，之后进入半开状态，默认 5s
+	HalfOpenProbes    int64         // HalfOpenProbes 半开状态允许通过的探测请求数，全部成功后恢复，默认 3
+}
+
+// DefaultBreakerConfig 默认熔断配置
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		Window:            xtime.Duration("10s"),
+		MinRequests:       20,
+		ErrorRatio:        0.5,
+		SlowCallThreshold: xtime.Duration("500ms"),
+		OpenTimeout:       xtime.Duration("5s"),
+		HalfOpenProbes:    3,
+	}
+}
+
+type breakerBucket struct {
+	start time.Time
+	total int64
+	fails int64
+	slows int64
+}
+
+// breaker 基于滑动窗口的熔断器
+type breaker struct {
+	name   string
+	node   string
+	config BreakerConfig
+	logger *elog.Component
+	now    func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	openedAt time.Time
+	buckets  [breakerBuckets]breakerBucket
+	probes   int64 // 半开状态下已放行的探测请求数
+	passed   int64 // 半开状态下已成功的探测请求数
+}
+
+func newBreaker(name, node string, config BreakerConfig, logger *elog.Component) *breaker {
+	b := &breaker{
+		name:   name,
+		node:   node,
+		config: config,
+		logger: logger,
+		now:    time.Now,
+		state:  BreakerClosed,
+	}
+	breakerStateGauge.Set(0, name, node)
+	return b
+}
+
+// State 返回熔断器当前状态
+func (b *breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// allow 判断是否放行请求，probe 表示该请求是半开状态下的探测请求
+func (b *breaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.config.OpenTimeout {
+			breakerRejectCounter.Inc(b.name, b.node)
+			return false, ErrCircuitOpen
+		}
+		b.setState(BreakerHalfOpen)
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probes >= b.config.HalfOpenProbes {
+			breakerRejectCounter.Inc(b.name, b.node)
+			return false, ErrCircuitOpen
+		}
+		b.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// done 记录请求结果
+func (b *breaker) done(probe bool, err error, cost time.Duration) {
+	fail := isBreakerFailure(err)
+	slow := b.config.SlowCallThreshold > 0 && cost > b.config.SlowCallThreshold
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		if b.state != BreakerHalfOpen {
+			return
+		}
+		if fail || (slow && b.config.SlowCallRatio > 0) {
+			b.setState(BreakerOpen)
+			return
+		}
+		b.passed++
+		if b.passed >= b.config.HalfOpenProbes {
+			b.setState(BreakerClosed)
+		}
+		return
+	}
+	if b.state != BreakerClosed {
+		return
+	}
+
+	bucket := b.bucket()
+	bucket.total++
+	if fail {
+		bucket.fails++
+	}
+	if slow {
+		bucket.slows++
+	}
+
+	var total, fails, slows int64
+	start := b.now().Add(-b.config.Window)
+	for i := range b.buckets {
+		if b.buckets[i].start.After(start) {
+			total += b.buckets[i].total
+			fails += b.buckets[i].fails
+			slows += b.buckets[i].slows
+		}
+	}
+	if total < b.config.MinRequests {
+		return
+	}
+	if (b.config.ErrorRatio > 0 && float64(fails)/float64(total) >= b.config.ErrorRatio) ||
+		(b.config.SlowCallRatio > 0 && float64(slows)/float64(total) >= b.config.SlowCallRatio) {
+		b.setState(BreakerOpen)
+	}
+}
+
+// bucket 返回当前时间所在的桶，过期的桶会被重置
+func (b *breaker) bucket() *breakerBucket {
+	width := b.config.Window / breakerBuckets
+	if width <= 0 {
+		width = time.Millisecond
+	}
+	now := b.now()
+	start := now.Truncate(width)
+	bucket := &b.buckets[(now.UnixNano()/int64(width))%breakerBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	return bucket
+}
+
+// setState 切换状态，调用方需持有锁
+func (b *breaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+	b.logger.Warn("circuit breaker state changed", elog.FieldName(b.name), elog.FieldAddr(b.node), elog.String("from", b.state), elog.String("to", state))
+	b.state = state
+	b.probes, b.passed = 0, 0
+	switch state {
+	case BreakerOpen:
+		b.openedAt = b.now()
+		breakerStateGauge.Set(1, b.name, b.node)
+	case BreakerHalfOpen:
+		breakerStateGauge.Set(2, b.name, b.node)
+	case BreakerClosed:
+		b.buckets = [breakerBuckets]breakerBucket{}
+		breakerStateGauge.Set(0, b.name, b.node)
+	}
+}
+
+// isBreakerFailure 只有基础设施类错误才计入熔断统计，key 不存在、WRONGTYPE 等业务错误不计入
+func isBreakerFailure(err error) bool {
+	if err == nil || IsNil(err) || errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	return IsTimeout(err) || IsConnectionError(err) || IsPoolExhausted(err) || IsReadOnly(err) || isClusterUnavailable(err)
+}
+
+type breakerCtxKey struct{}
+
+type breakerCall struct {
+	start    time.Time
+	probe    bool
+	blocking bool // blocking 阻塞命令的耗时不计入慢调用
+}
+
+// breakerInterceptor 熔断拦截器，熔断期间命令不会发送到 redis，直接返回 ErrCircuitOpen
+func breakerInterceptor(b *breaker) *interceptor {
+	before := func(ctx context.Context, cmds ...redis.Cmder) (context.Context, error) {
+		probe, err := b.allow()
+		if err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return ctx, err
+		}
+		call := &breakerCall{start: time.Now(), probe: probe}
+		for _, cmd := range cmds {
+			if isBlockingCommand(cmd) {
+				call.blocking = true
+				break
+			}
+		}
+		return context.WithValue(ctx, breakerCtxKey{}, call), nil
+	}
+	after := func(ctx context.Context, err error) error {
+		if call, ok := ctx.Value(breakerCtxKey{}).(*breakerCall); ok {
+			cost := time.Since(call.start)
+			if call.blocking {
+				cost = 0
+			}
+			b.done(call.probe, err, cost)
+		}
+		return err
+	}
+
+	return newInterceptor(b.name, nil, b.logger).
+		setBeforeProcess(func(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+			return before(ctx, cmd)
+		}).
+		setAfterProcess(func(ctx context.Context, cmd redis.Cmder) error {
+			return after(ctx, cmd.Err())
+		}).
+		setBeforeProcessPipeline(func(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+			return before(ctx, cmds...)
+		}).
+		setAfterProcessPipeline(func(ctx context.Context, cmds []redis.Cmder) error {
+			for _, cmd := range cmds {
+				if err := cmd.Err(); isBreakerFailure(err) {
+					return after(ctx, err)
+				}
+			}
+			return after(ctx, nil)
+		})
+}
+
+// isBlockingCommand 阻塞命令会在服务端等待数据，耗时不代表 redis 变慢
+func isBlockingCommand(cmd redis.Cmder) bool {
+	switch name := strings.ToLower(cmd.Name()); name {
+	case "blpop", "brpop", "brpoplpush", "blmove", "blmpop", "bzpopmin", "bzpopmax", "bzmpop", "wait", "waitaof":
+		return true
+	case "xread", "xreadgroup":
+		for _, arg := range cmd.Args()[1:] {
+			if s, ok := arg.(string); ok && strings.EqualFold(s, "block") {
+				return true
+			}
+		}
+	}
+	return false
+}
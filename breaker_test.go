@@ -0,0 +1,120 @@
+package eredis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	config := DefaultBreakerConfig()
+	config.MinRequests = 4
+	b := newBreaker("test", "127.0.0.1:6379", config, elog.DefaultLogger)
+	b.now = func() time.Time { return now }
+
+	// 业务错误不计入熔断统计
+	for i := 0; i < 10; i++ {
+		b.done(false, redis.Nil, time.Millisecond)
+	}
+	assert.Equal(t, BreakerClosed, b.State())
+
+	for i := 0; i < 10; i++ {
+		b.done(false, context.DeadlineExceeded, time.Millisecond)
+	}
+	assert.Equal(t, BreakerOpen, b.State())
+	_, err := b.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// 熔断时间过后进入半开状态，只放行 HalfOpenProbes 个探测请求
+	now = now.Add(config.OpenTimeout)
+	for i := int64(0); i < config.HalfOpenProbes; i++ {
+		probe, err := b.allow()
+		assert.NoError(t, err)
+		assert.True(t, probe)
+	}
+	assert.Equal(t, BreakerHalfOpen, b.State())
+	_, err = b.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// 探测失败重新熔断
+	b.done(true, context.DeadlineExceeded, time.Millisecond)
+	assert.Equal(t, BreakerOpen, b.State())
+
+	// 探测全部成功后恢复
+	now = now.Add(config.OpenTimeout)
+	for i := int64(0); i < config.HalfOpenProbes; i++ {
+		probe, err := b.allow()
+		assert.NoError(t, err)
+		b.done(probe, nil, time.Millisecond)
+	}
+	assert.Equal(t, BreakerClosed, b.State())
+}
+
+func TestBreakerSlowCall(t *testing.T) {
+	now := time.Now()
+	config := DefaultBreakerConfig()
+	config.MinRequests = 4
+	config.SlowCallRatio = 0.5
+	b := newBreaker("test", "127.0.0.1:6380", config, elog.DefaultLogger)
+	b.now = func() time.Time { return now }
+
+	b.done(false, nil, time.Millisecond)
+	b.done(false, nil, time.Millisecond)
+	b.done(false, nil, time.Second)
+	assert.Equal(t, BreakerClosed, b.State())
+	b.done(false, nil, time.Second)
+	assert.Equal(t, BreakerOpen, b.State())
+}
+
+func TestBreakerWindow(t *testing.T) {
+	now := time.Now()
+	config := DefaultBreakerConfig()
+	config.MinRequests = 4
+	b := newBreaker("test", "127.0.0.1:6381", config, elog.DefaultLogger)
+	b.now = func() time.Time { return now }
+
+	b.done(false, context.DeadlineExceeded, time.Millisecond)
+	b.done(false, context.DeadlineExceeded, time.Millisecond)
+	// 超出统计窗口的错误不再计入
+	now = now.Add(config.Window + time.Second)
+	b.done(false, nil, time.Millisecond)
+	b.done(false, nil, time.Millisecond)
+	b.done(false, context.DeadlineExceeded, time.Millisecond)
+	b.done(false, nil, time.Millisecond)
+	assert.Equal(t, BreakerClosed, b.State())
+}
+
+func TestBreakerInterceptor(t *testing.T) {
+	config := DefaultBreakerConfig()
+	config.MinRequests = 4
+	b := newBreaker("test", "127.0.0.1:1", config, elog.DefaultLogger)
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	defer client.Close()
+	client.AddHook(breakerInterceptor(b))
+
+	// 单个命令的连接错误经过 hook 计入熔断统计
+	ctx := context.Background()
+	var err error
+	for i := 0; i < 10; i++ {
+		if err = client.Get(ctx, "a").Err(); errors.Is(err, ErrCircuitOpen) {
+			break
+		}
+	}
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, BreakerOpen, b.State())
+}
+
+func TestIsBlockingCommand(t *testing.T) {
+	ctx := context.Background()
+	assert.True(t, isBlockingCommand(redis.NewCmd(ctx, "blmove", "a", "b", "right", "left", 5)))
+	assert.True(t, isBlockingCommand(redis.NewCmd(ctx, "bzpopmin", "a", 1)))
+	assert.True(t, isBlockingCommand(redis.NewCmd(ctx, "xreadgroup", "group", "g", "c", "block", 1000, "streams", "s", ">")))
+	assert.False(t, isBlockingCommand(redis.NewCmd(ctx, "xreadgroup", "group", "g", "c", "streams", "s", ">")))
+	assert.False(t, isBlockingCommand(redis.NewCmd(ctx, "get", "a")))
+}
@@ -0,0 +1,289 @@
+package eredis
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	cacheLockPrefix = "eredis:cache:lock:"
+	// cacheNilValue 负缓存的占位值
+	cacheNilValue = "\x00eredis:cache:nil"
+)
+
+// Loader 缓存未命中时从数据源加载数据，数据不存在时返回 Nil，会被负缓存 NegativeTTL
+type Loader func(ctx context.Context) (string, error)
+
+// CacheOption 缓存选项
+type CacheOption func(c *Cache)
+
+// WithCacheJitter 设置过期时间的随机抖动比例，避免大量 key 同时过期，默认 0.1
+func WithCacheJitter(ratio float64) CacheOption {
+	return func(c *Cache) {
+		c.jitter = ratio
+	}
+}
+
+// WithCacheNegativeTTL 设置数据不存在时负缓存的过期时间，0 表示不缓存，默认 30s
+func WithCacheNegativeTTL(ttl time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.negativeTTL = ttl
+	}
+}
+
+// WithCacheLockTTL 设置分布式重建锁的过期时间，未抢到锁的请求最多等待该时间，默认 3s
+func WithCacheLockTTL(ttl time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.lockTTL = ttl
+	}
+}
+
+// WithCacheLoadTimeout 设置 loader 的超时时间，默认与 LockTTL 相同
+func WithCacheLoadTimeout(timeout time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.loadTimeout = timeout
+	}
+}
+
+// WithCacheEarlyRefresh 设置概率提前刷新的系数 beta，越大越倾向于提前刷新，0 表示不提前刷新，默认 1
+func WithCacheEarlyRefresh(beta float64) CacheOption {
+	return func(c *Cache) {
+		c.beta = beta
+	}
+}
+
+// Cache 基于 Component 的 cache-aside 缓存：
+//   - 进程内通过 singleflight 合并同一个 key 的并发加载
+//   - 进程间通过分布式锁保证同一时刻只有一个实例重建缓存，其他实例等待缓存写入
+//   - 过期时间增加随机抖动，数据不存在时写入负缓存
+//   - 按 XFetch 算法在过期前概率性地异步刷新，避免热点 key 过期瞬间的击穿
+type Cache struct {
+	comp        *Component
+	group       singleflight.Group
+	jitter      float64
+	negativeTTL time.Duration
+	lockTTL     time.Duration
+	loadTimeout time.Duration
+	beta        float64
+	refreshing  sync.Map // refreshing 正在提前刷新的 key，与未命中时的加载互不影响
+
+	mu    sync.Mutex
+	delta time.Duration // delta 最近加载耗时的滑动平均，用于提前刷新
+}
+
+// NewCache 创建 cache-aside 缓存
+func (r *Component) NewCache(opts ...CacheOption) *Cache {
+	c := &Cache{
+		comp:        r,
+		jitter:      0.1,
+		negativeTTL: 30 * time.Second,
+		lockTTL:     3 * time.Second,
+		beta:        1,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.loadTimeout <= 0 {
+		c.loadTimeout = c.lockTTL
+	}
+	return c
+}
+
+// GetOrLoad 读取缓存，未命中时调用 loader 加载并写入缓存，过期时间为 ttl 加上随机抖动
+func (c *Cache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, loader Loader) (string, error) {
+	value, pttl, err := c.get(ctx, key)
+	switch {
+	case err == nil:
+		if c.shouldRefresh(pttl) {
+			c.refresh(key, ttl, loader)
+		}
+		if value == cacheNilValue {
+			c.metric("negative_hit")
+			return "", c.comp.wrapErr("get", Nil)
+		}
+		c.metric("hit")
+		return value, nil
+	case !IsNil(err):
+		c.metric("error")
+		return "", c.comp.wrapErr("get", err)
+	}
+
+	c.metric("miss")
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		// 合并的请求共用一次加载，加载不能随第一个调用方的 ctx 取消，等待重建锁的时间由 lockTTL 限制，loader 的时间由 loadTimeout 限制
+		return c.rebuild(detachedContext{parent: ctx}, key, ttl, loader)
+	})
+	var res singleflight.Result
+	select {
+	case <-ctx.Done():
+		return "", c.comp.wrapErr("get", ctx.Err())
+	case res = <-ch:
+	}
+	if res.Err != nil {
+		return "", res.Err
+	}
+	if res.Val.(string) == cacheNilValue {
+		return "", c.comp.wrapErr("get", Nil)
+	}
+	return res.Val.(string), nil
+}
+
+// Delete 删除缓存，数据源更新后调用
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	_, err := c.comp.del(ctx, keys)
+	return c.comp.wrapErr("del", err)
+}
+
+// get 读取缓存值和剩余过期时间
+func (c *Cache) get(ctx context.Context, key string) (string, time.Duration, error) {
+	var (
+		getCmd  *redis.StringCmd
+		pttlCmd *redis.DurationCmd
+	)
+	_, err := c.comp.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		getCmd = pipe.Get(ctx, key)
+		pttlCmd = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	value, err := c.comp.decodeRaw(key, "", getCmd.Val())
+	return value, pttlCmd.Val(), err
+}
+
+// rebuild 获取分布式锁后加载数据并写入缓存，未获取到锁时等待其他实例写入缓存
+func (c *Cache) rebuild(ctx context.Context, key string, ttl time.Duration, loader Loader) (string, error) {
+	lock, err := c.comp.lockClient.Obtain(ctx, cacheLockPrefix+key, c.lockTTL)
+	if err == nil {
+		defer lock.Release(context.Background())
+		// 抢到锁之前其他实例可能已经写入了缓存
+		if value, err := c.comp.Get(ctx, key); err == nil {
+			return value, nil
+		}
+		return c.load(ctx, key, ttl, loader)
+	}
+	if !errors.Is(err, ErrNotObtained) {
+		c.comp.logger.Warn("cache obtain lock fail", elog.FieldErr(err), elog.FieldKey(key))
+		return c.load(ctx, key, ttl, loader)
+	}
+
+	// 其他实例正在重建缓存，轮询等待缓存写入，超时后自行加载
+	ticker := time.NewTicker(c.lockTTL / 30)
+	defer ticker.Stop()
+	deadline := time.NewTimer(c.lockTTL)
+	defer deadline.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return "", c.comp.wrapErr("get", ctx.Err())
+		case <-deadline.C:
+			return c.load(ctx, key, ttl, loader)
+		case <-ticker.C:
+			if value, err := c.comp.Get(ctx, key); err == nil {
+				return value, nil
+			}
+		}
+	}
+}
+
+// load 调用 loader 并写入缓存
+func (c *Cache) load(ctx context.Context, key string, ttl time.Duration, loader Loader) (string, error) {
+	start := time.Now()
+	loadCtx, cancel := context.WithTimeout(ctx, c.loadTimeout)
+	value, err := loader(loadCtx)
+	cancel()
+	cost := time.Since(start)
+	emetric.CacheHandleHistogram.Observe(cost.Seconds(), emetric.TypeRedis, c.comp.name, "load")
+	c.observe(cost)
+
+	if err != nil {
+		if !IsNil(err) || c.negativeTTL <= 0 {
+			c.metric("load_error")
+			return "", err
+		}
+		value, ttl = cacheNilValue, c.negativeTTL
+	}
+	c.metric("load")
+
+	if err := c.comp.Set(ctx, key, value, c.withJitter(ttl)); err != nil {
+		c.comp.logger.Warn("cache set fail", elog.FieldErr(err), elog.FieldKey(key))
+	}
+	return value, nil
+}
+
+// refresh 异步刷新缓存，同一个 key 同时只会有一个刷新任务，已经在刷新时直接返回。
+// 没有抢到重建锁说明其他实例正在刷新，放弃本次刷新
+func (c *Cache) refresh(key string, ttl time.Duration, loader Loader) {
+	if _, loaded := c.refreshing.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	c.metric("early_refresh")
+	go func() {
+		defer c.refreshing.Delete(key)
+		ctx, cancel := context.WithTimeout(context.Background(), c.lockTTL+c.loadTimeout)
+		defer cancel()
+		lock, err := c.comp.lockClient.Obtain(ctx, cacheLockPrefix+key, c.lockTTL)
+		if err != nil {
+			return
+		}
+		defer lock.Release(context.Background())
+		_, _ = c.load(ctx, key, ttl, loader)
+	}()
+}
+
+// shouldRefresh XFetch 算法：-delta * beta * ln(rand) >= 剩余过期时间时提前刷新
+func (c *Cache) shouldRefresh(pttl time.Duration) bool {
+	if c.beta <= 0 || pttl <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	delta := c.delta
+	c.mu.Unlock()
+	if delta <= 0 {
+		return false
+	}
+	return -float64(delta)*c.beta*math.Log(rand.Float64()) >= float64(pttl)
+}
+
+// observe 更新加载耗时的滑动平均
+func (c *Cache) observe(cost time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.delta == 0 {
+		c.delta = cost
+		return
+	}
+	c.delta = (c.delta*7 + cost) / 8
+}
+
+func (c *Cache) withJitter(ttl time.Duration) time.Duration {
+	if c.jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Float64()*c.jitter*float64(ttl))
+}
+
+// detachedContext 保留 parent 中的值（例如 trace），但不继承 parent 的取消和截止时间，
+// 作用与 go1.21 的 context.WithoutCancel 相同
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
+
+func (c *Cache) metric(code string) {
+	emetric.CacheHandleCounter.Inc(emetric.TypeRedis, c.comp.name, "getorload", code)
+}
@@ -0,0 +1,242 @@
+package eredis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheJitter(t *testing.T) {
+	c := (&Component{}).NewCache(WithCacheJitter(0.2))
+	for i := 0; i < 100; i++ {
+		ttl := c.withJitter(time.Minute)
+		assert.GreaterOrEqual(t, ttl, time.Minute)
+		assert.LessOrEqual(t, ttl, time.Minute+12*time.Second)
+	}
+	assert.Equal(t, time.Duration(0), c.withJitter(0))
+}
+
+func TestCacheShouldRefresh(t *testing.T) {
+	c := (&Component{}).NewCache()
+	// 没有加载耗时数据时不提前刷新
+	assert.False(t, c.shouldRefresh(time.Millisecond))
+
+	c.observe(100 * time.Millisecond)
+	// 剩余时间远大于加载耗时时几乎不会刷新，远小于时几乎总会刷新
+	far, near := 0, 0
+	for i := 0; i < 1000; i++ {
+		if c.shouldRefresh(time.Hour) {
+			far++
+		}
+		if c.shouldRefresh(time.Microsecond) {
+			near++
+		}
+	}
+	assert.Equal(t, 0, far)
+	assert.Greater(t, near, 990)
+
+	c = (&Component{}).NewCache(WithCacheEarlyRefresh(0))
+	c.observe(time.Second)
+	assert.False(t, c.shouldRefresh(time.Microsecond))
+}
+
+func TestDetachedContext(t *testing.T) {
+	type ctxKey struct{}
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "v"))
+	cancel()
+	ctx := detachedContext{parent: parent}
+	assert.NoError(t, ctx.Err())
+	assert.Nil(t, ctx.Done())
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+	assert.Equal(t, "v", ctx.Value(ctxKey{}))
+}
+
+func TestCacheGetOrLoad(t *testing.T) {
+	comp := newTestRedis(t, "redis.cacheTest")
+	c := comp.NewCache(WithCacheEarlyRefresh(0))
+	ctx := context.Background()
+	key := newTestKey(t, comp, "cache")
+
+	var calls int32
+	loader := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "v1", nil
+	}
+	// 未命中时加载并写入缓存，之后直接命中
+	for i := 0; i < 2; i++ {
+		value, err := c.GetOrLoad(ctx, key, time.Minute, loader)
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", value)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	ttl, err := comp.TTL(ctx, key)
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Second)
+
+	// loader 的错误直接返回，不写入缓存
+	assert.NoError(t, c.Delete(ctx, key))
+	boom := errors.New("boom")
+	_, err = c.GetOrLoad(ctx, key, time.Minute, func(ctx context.Context) (string, error) { return "", boom })
+	assert.ErrorIs(t, err, boom)
+	exists, err := comp.Exists(ctx, key)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestCacheNegative(t *testing.T) {
+	comp := newTestRedis(t, "redis.cacheTest")
+	c := comp.NewCache(WithCacheEarlyRefresh(0), WithCacheNegativeTTL(time.Minute))
+	ctx := context.Background()
+	key := newTestKey(t, comp, "cache")
+
+	var calls int32
+	loader := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "", Nil
+	}
+	for i := 0; i < 2; i++ {
+		_, err := c.GetOrLoad(ctx, key, time.Hour, loader)
+		assert.True(t, IsNil(err))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	raw, err := comp.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, cacheNilValue, raw)
+	ttl, err := comp.TTL(ctx, key)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Minute+6*time.Second)
+}
+
+func TestCacheSingleflight(t *testing.T) {
+	comp := newTestRedis(t, "redis.cacheTest")
+	c := comp.NewCache(WithCacheEarlyRefresh(0))
+	key := newTestKey(t, comp, "cache")
+
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-release:
+			return "v1", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	// 第一个调用方取消之后，加载仍然完成，其他合并的调用方拿到结果
+	firstCtx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(firstCtx, key, time.Minute, loader)
+		firstErr <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	values := make([]string, 10)
+	for i := range values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], _ = c.GetOrLoad(context.Background(), key, time.Minute, loader)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, value := range values {
+		assert.Equal(t, "v1", value)
+	}
+}
+
+func TestCacheRebuildLock(t *testing.T) {
+	comp := newTestRedis(t, "redis.cacheTest")
+	c := comp.NewCache(WithCacheEarlyRefresh(0), WithCacheLockTTL(time.Second))
+	ctx := context.Background()
+	key := newTestKey(t, comp, "cache")
+
+	// 其他实例持有重建锁时等待其写入缓存，不调用 loader
+	lock, err := comp.lockClient.Obtain(ctx, cacheLockPrefix+key, time.Second)
+	assert.NoError(t, err)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = comp.Set(ctx, key, "other", time.Minute)
+		_ = lock.Release(ctx)
+	}()
+	var calls int32
+	value, err := c.GetOrLoad(ctx, key, time.Minute, func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "v1", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "other", value)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+
+	// 持有锁的实例没有写入缓存时，等待 LockTTL 后自行加载
+	assert.NoError(t, c.Delete(ctx, key))
+	_, err = comp.lockClient.Obtain(ctx, cacheLockPrefix+key, time.Second)
+	assert.NoError(t, err)
+	value, err = c.GetOrLoad(ctx, key, time.Minute, func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "v1", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", value)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestCacheEarlyRefresh(t *testing.T) {
+	comp := newTestRedis(t, "redis.cacheTest")
+	c := comp.NewCache(WithCacheEarlyRefresh(1e9))
+	c.delta = time.Hour
+	ctx := context.Background()
+	key := newTestKey(t, comp, "cache")
+	assert.NoError(t, comp.Set(ctx, key, "v0", time.Minute))
+
+	// 命中时提前刷新，同一个 key 正在刷新时不会重复刷新
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	refresh := func(ctx context.Context) (string, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return "v1", nil
+	}
+	for i := 0; i < 10; i++ {
+		value, err := c.GetOrLoad(ctx, key, time.Minute, refresh)
+		assert.NoError(t, err)
+		assert.Equal(t, "v0", value)
+	}
+	<-started
+
+	// 刷新期间未命中的请求不会合并到刷新任务中，等待持有重建锁的刷新写入缓存
+	assert.NoError(t, c.Delete(ctx, key))
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+	}()
+	value, err := c.GetOrLoad(ctx, key, time.Minute, func(ctx context.Context) (string, error) {
+		t.Error("loader should not be called while the refresh holds the lock")
+		return "", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", value)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Eventually(t, func() bool {
+		_, ok := c.refreshing.Load(key)
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,99 @@
+package eredis
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// 内置编解码器名称
+const (
+	CodecJSON    = "json"
+	CodecMsgpack = "msgpack"
+	CodecProto   = "proto"
+)
+
+// Codec 值编解码器，用于 GetValue、SetValue 等泛型方法，通过 config 的 Codec 或 WithCodec 配置
+type Codec interface {
+	// Name 编解码器名称，用于错误信息
+	Name() string
+	// Marshal 编码
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal 解码，v 为指针
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var codecs = map[string]Codec{
+	CodecJSON:    jsonCodec{},
+	CodecMsgpack: msgpackCodec{},
+	CodecProto:   protoCodec{},
+}
+
+// RegisterCodec 注册编解码器，之后可以在 config 的 Codec 中使用，需要在 Build 之前调用
+func RegisterCodec(codec Codec) {
+	codecs[codec.Name()] = codec
+}
+
+// GetCodec 根据名称获取编解码器
+func GetCodec(name string) (Codec, bool) {
+	codec, ok := codecs[name]
+	return codec, ok
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return CodecJSON }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string { return CodecMsgpack }
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) { return msgpack.Marshal(v) }
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
+
+type protoCodec struct{}
+
+func (protoCodec) Name() string { return CodecProto }
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("proto codec: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("proto codec: %T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// DecodeError 解码失败的错误，记录了 key 和编解码器，可以通过 errors.As 获取
+type DecodeError struct {
+	Key   string // Key redis key
+	Field string // Field hash 的 field，非 hash 时为空
+	Codec string // Codec 编解码器名称
+	Err   error  // Err 原始错误
+}
+
+func (e *DecodeError) Error() string {
+	if e.Field != "" {
+		return "eredis: decode key " + e.Key + " field " + e.Field + " with " + e.Codec + ": " + e.Err.Error()
+	}
+	return "eredis: decode key " + e.Key + " with " + e.Codec + ": " + e.Err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
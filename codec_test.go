@@ -0,0 +1,55 @@
+package eredis
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type codecUser struct {
+	Name string `json:"name" msgpack:"name"`
+	Age  int    `json:"age" msgpack:"age"`
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, name := range []string{CodecJSON, CodecMsgpack} {
+		codec, ok := GetCodec(name)
+		assert.True(t, ok)
+		data, err := codec.Marshal(codecUser{Name: "ego", Age: 3})
+		assert.NoError(t, err)
+
+		user, err := decodeValue[codecUser](codec, "user:1", "", data)
+		assert.NoError(t, err)
+		assert.Equal(t, codecUser{Name: "ego", Age: 3}, user)
+
+		ptr, err := decodeValue[*codecUser](codec, "user:1", "", data)
+		assert.NoError(t, err)
+		assert.Equal(t, "ego", ptr.Name)
+	}
+
+	codec, _ := GetCodec(CodecProto)
+	data, err := codec.Marshal(wrapperspb.String("ego"))
+	assert.NoError(t, err)
+	msg, err := decodeValue[*wrapperspb.StringValue](codec, "msg", "", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "ego", msg.GetValue())
+
+	_, err = codec.Marshal(codecUser{})
+	assert.Error(t, err)
+}
+
+func TestDecodeError(t *testing.T) {
+	_, err := decodeValue[codecUser](codecs[CodecJSON], "user:1", "profile", []byte("{"))
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "user:1", de.Key)
+	assert.Equal(t, CodecJSON, de.Codec)
+	assert.Contains(t, err.Error(), "decode key user:1 field profile with json")
+
+	code, reason := GRPCCode(&Error{Cmd: "get", Err: err})
+	assert.Equal(t, codes.Internal, code)
+	assert.Equal(t, ReasonDecode, reason)
+}
@@ -2,7 +2,6 @@ package eredis
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -10,41 +9,34 @@ import (
 
 // Ping
 func (r *Component) Ping(ctx context.Context) (string, error) {
-	return r.client.Ping(ctx).Result()
+	cmd := r.client.Ping(ctx)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // Get
 func (r *Component) Get(ctx context.Context, key string) (string, error) {
-	reply, err := r.client.Get(ctx, key).Result()
-	if err != nil {
-		return reply, fmt.Errorf("eredis get string error %w", err)
-	}
-	return reply, err
+	return r.stringValue(r.client.Get(ctx, key), key, "")
 }
 
 // GETEX
 func (r *Component) GetEx(ctx context.Context, key string, expire time.Duration) (string, error) {
-	reply, err := r.client.GetEx(ctx, key, expire).Result()
-	if err != nil {
-		return reply, fmt.Errorf("eredis get string error %w", err)
-	}
-	return reply, err
+	return r.stringValue(r.client.GetEx(ctx, key, expire), key, "")
 }
 
 // GetBytes
 func (r *Component) GetBytes(ctx context.Context, key string) ([]byte, error) {
-	c, err := r.client.Get(ctx, key).Bytes()
+	value, err := r.stringValue(r.client.Get(ctx, key), key, "")
 	if err != nil {
-		return c, fmt.Errorf("eredis get bytes error %w", err)
+		return nil, err
 	}
-	return c, nil
+	return []byte(value), nil
 }
 
 // MGet ...
 func (r *Component) MGetString(ctx context.Context, keys ...string) ([]string, error) {
-	reply, err := r.client.MGet(ctx, keys...).Result()
+	reply, err := r.mgetValues(ctx, keys)
 	if err != nil {
-		return []string{}, fmt.Errorf("eredis mgetstring error %w", err)
+		return []string{}, r.wrapErr("mget", err)
 	}
 	strSlice := make([]string, 0, len(reply))
 	for _, v := range reply {
@@ -59,42 +51,89 @@ func (r *Component) MGetString(ctx context.Context, keys ...string) ([]string, e
 
 // MGets ...
 func (r *Component) MGet(ctx context.Context, keys ...string) ([]interface{}, error) {
-	return r.client.MGet(ctx, keys...).Result()
+	reply, err := r.mgetValues(ctx, keys)
+	return reply, r.wrapErr("mget", err)
+}
+
+// mgetValues 批量获取并还原值
+func (r *Component) mgetValues(ctx context.Context, keys []string) ([]interface{}, error) {
+	reply, err := r.mget(ctx, keys)
+	if err != nil || len(r.transformers) == 0 {
+		return reply, err
+	}
+	for i, v := range reply {
+		if reply[i], err = r.decodeReply(keys[i], "", v); err != nil {
+			return nil, err
+		}
+	}
+	return reply, nil
 }
 
 // Set 设置redis的string
 func (r *Component) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
-	return r.client.Set(ctx, key, value, expire).Err()
+	v, err := r.encodeRaw(key, "", value)
+	if err != nil {
+		return r.wrapErr("set", err)
+	}
+	return r.cmdErr(r.client.Set(ctx, key, v, expire))
 }
 
 // SetEX ...
 func (r *Component) SetEX(ctx context.Context, key string, value interface{}, expire time.Duration) error {
-	return r.client.SetEx(ctx, key, value, expire).Err()
+	v, err := r.encodeRaw(key, "", value)
+	if err != nil {
+		return r.wrapErr("setex", err)
+	}
+	return r.cmdErr(r.client.SetEx(ctx, key, v, expire))
 }
 
 // SetNX ...
 func (r *Component) SetNX(ctx context.Context, key string, value interface{}, expire time.Duration) error {
-	return r.client.SetNX(ctx, key, value, expire).Err()
+	v, err := r.encodeRaw(key, "", value)
+	if err != nil {
+		return r.wrapErr("setnx", err)
+	}
+	return r.cmdErr(r.client.SetNX(ctx, key, v, expire))
 }
 
 // HGetAll 从redis获取hash的所有键值对
 func (r *Component) HGetAll(ctx context.Context, key string) (map[string]string, error) {
-	return r.client.HGetAll(ctx, key).Result()
+	cmd := r.hgetAll(ctx, key)
+	return cmd.Val(), r.cmdErr(cmd)
+}
+
+// hgetAll 获取 hash 的所有键值对并还原值，还原失败时错误记录在 cmd 中
+func (r *Component) hgetAll(ctx context.Context, key string) *redis.MapStringStringCmd {
+	cmd := r.client.HGetAll(ctx, key)
+	if cmd.Err() != nil || len(r.transformers) == 0 {
+		return cmd
+	}
+	hash := make(map[string]string, len(cmd.Val()))
+	for field, value := range cmd.Val() {
+		v, err := r.decodeRaw(key, field, value)
+		if err != nil {
+			cmd.SetErr(err)
+			return cmd
+		}
+		hash[field] = v
+	}
+	cmd.SetVal(hash)
+	return cmd
 }
 
 // HGet 从redis获取hash单个值
 func (r *Component) HGet(ctx context.Context, key string, fields string) (string, error) {
-	return r.client.HGet(ctx, key, fields).Result()
+	return r.stringValue(r.client.HGet(ctx, key, fields), key, fields)
 }
 
 // HMGetMap 批量获取hash值，返回map
 func (r *Component) HMGetMap(ctx context.Context, key string, fields []string) (map[string]string, error) {
 	if len(fields) == 0 {
-		return make(map[string]string), fmt.Errorf("eredis hmgetmap error %w", ErrInvalidParams)
+		return make(map[string]string), r.wrapErr("hmget", ErrInvalidParams)
 	}
-	reply, err := r.client.HMGet(ctx, key, fields...).Result()
+	reply, err := r.hmget(ctx, key, fields)
 	if err != nil {
-		return make(map[string]string), fmt.Errorf("eredis hmgetmap error %w", err)
+		return make(map[string]string), r.wrapErr("hmget", err)
 	}
 
 	hashRet := make(map[string]string, len(reply))
@@ -114,112 +153,135 @@ func (r *Component) HMGetMap(ctx context.Context, key string, fields []string) (
 // HMSet 设置redis的hash
 func (r *Component) HMSet(ctx context.Context, key string, hash map[string]interface{}, expire time.Duration) error {
 	if len(hash) == 0 {
-		return fmt.Errorf("eredis hmset error %w", ErrInvalidParams)
+		return r.wrapErr("hmset", ErrInvalidParams)
 	}
 
-	err := r.client.HMSet(ctx, key, hash).Err()
+	hash, err := r.encodeHash(key, hash)
 	if err != nil {
+		return r.wrapErr("hmset", err)
+	}
+	if err := r.cmdErr(r.client.HMSet(ctx, key, hash)); err != nil {
 		return err
 	}
 	if expire > 0 {
-		err = r.client.Expire(ctx, key, expire).Err()
-		if err != nil {
-			return fmt.Errorf("eredis hmset expire error %w", err)
-		}
+		return r.cmdErr(r.client.Expire(ctx, key, expire))
 	}
 	return nil
 }
 
 // HSet hset
 func (r *Component) HSet(ctx context.Context, key string, field string, value interface{}) error {
-	return r.client.HSet(ctx, key, field, value).Err()
+	v, err := r.encodeRaw(key, field, value)
+	if err != nil {
+		return r.wrapErr("hset", err)
+	}
+	return r.cmdErr(r.client.HSet(ctx, key, field, v))
 }
 
 // HDel ...
 func (r *Component) HDel(ctx context.Context, key string, field ...string) error {
-	return r.client.HDel(ctx, key, field...).Err()
+	return r.cmdErr(r.client.HDel(ctx, key, field...))
 }
 
 // SetNx 设置redis的string 如果键已存在
 func (r *Component) SetNx(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
-	return r.client.SetNX(ctx, key, value, expiration).Result()
+	v, err := r.encodeRaw(key, "", value)
+	if err != nil {
+		return false, r.wrapErr("setnx", err)
+	}
+	cmd := r.client.SetNX(ctx, key, v, expiration)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // Incr redis自增
 func (r *Component) Incr(ctx context.Context, key string) (int64, error) {
-	return r.client.Incr(ctx, key).Result()
+	cmd := r.client.Incr(ctx, key)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // IncrBy 将 key 所储存的值加上增量 increment 。
 func (r *Component) IncrBy(ctx context.Context, key string, increment int64) (int64, error) {
-	return r.client.IncrBy(ctx, key, increment).Result()
+	cmd := r.client.IncrBy(ctx, key, increment)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // Decr redis自减
 func (r *Component) Decr(ctx context.Context, key string) (int64, error) {
-	return r.client.Decr(ctx, key).Result()
+	cmd := r.client.Decr(ctx, key)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // Decr redis自减特定的值
 func (r *Component) DecrBy(ctx context.Context, key string, decrement int64) (int64, error) {
-	return r.client.DecrBy(ctx, key, decrement).Result()
+	cmd := r.client.DecrBy(ctx, key, decrement)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // Type ...
 func (r *Component) Type(ctx context.Context, key string) (string, error) {
-	return r.client.Type(ctx, key).Result()
+	cmd := r.client.Type(ctx, key)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // ZRevRange 倒序获取有序集合的部分数据
 func (r *Component) ZRevRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
-	return r.client.ZRevRange(ctx, key, start, stop).Result()
+	cmd := r.client.ZRevRange(ctx, key, start, stop)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // ZRevRangeWithScores ...
 func (r *Component) ZRevRangeWithScores(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
-	return r.client.ZRevRangeWithScores(ctx, key, start, stop).Result()
+	cmd := r.client.ZRevRangeWithScores(ctx, key, start, stop)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // ZRange ...
 func (r *Component) ZRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
-	return r.client.ZRange(ctx, key, start, stop).Result()
+	cmd := r.client.ZRange(ctx, key, start, stop)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // ZRangeByScore ...
 func (r *Component) ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) ([]string, error) {
-	return r.client.ZRangeByScore(ctx, key, opt).Result()
+	cmd := r.client.ZRangeByScore(ctx, key, opt)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // ZRangeWithScores ...
 func (r *Component) ZRangeWithScores(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
-	return r.client.ZRangeWithScores(ctx, key, start, stop).Result()
+	cmd := r.client.ZRangeWithScores(ctx, key, start, stop)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // ZRangeByScoreWithScores ...
 func (r *Component) ZRangeByScoreWithScores(ctx context.Context, key string, opt *redis.ZRangeBy) ([]redis.Z, error) {
-	return r.client.ZRangeByScoreWithScores(ctx, key, opt).Result()
+	cmd := r.client.ZRangeByScoreWithScores(ctx, key, opt)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // ZRevRank ...
 func (r *Component) ZRevRank(ctx context.Context, key string, member string) (int64, error) {
-	return r.client.ZRevRank(ctx, key, member).Result()
+	cmd := r.client.ZRevRank(ctx, key, member)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // ZRevRangeByScore ...
 func (r *Component) ZRevRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) ([]string, error) {
-	return r.client.ZRevRangeByScore(ctx, key, opt).Result()
+	cmd := r.client.ZRevRangeByScore(ctx, key, opt)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // ZRevRangeByScoreWithScores ...
 func (r *Component) ZRevRangeByScoreWithScores(ctx context.Context, key string, opt *redis.ZRangeBy) ([]redis.Z, error) {
-	return r.client.ZRevRangeByScoreWithScores(ctx, key, opt).Result()
+	cmd := r.client.ZRevRangeByScoreWithScores(ctx, key, opt)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // HMGet 批量获取hash值
 func (r *Component) HMGetString(ctx context.Context, key string, fileds []string) ([]string, error) {
-	reply, err := r.client.HMGet(ctx, key, fileds...).Result()
+	reply, err := r.hmget(ctx, key, fileds)
 	if err != nil {
-		return []string{}, fmt.Errorf("hmgetstring err %w", err)
+		return []string{}, r.wrapErr("hmget", err)
 	}
 	strSlice := make([]string, 0, len(reply))
 	for _, v := range reply {
@@ -233,151 +295,204 @@ func (r *Component) HMGetString(ctx context.Context, key string, fileds []string
 }
 
 func (r *Component) HMGet(ctx context.Context, key string, fileds []string) ([]interface{}, error) {
-	return r.client.HMGet(ctx, key, fileds...).Result()
+	reply, err := r.hmget(ctx, key, fileds)
+	return reply, r.wrapErr("hmget", err)
+}
+
+// hmget 批量获取 hash 值并还原
+func (r *Component) hmget(ctx context.Context, key string, fields []string) ([]interface{}, error) {
+	reply, err := r.client.HMGet(ctx, key, fields...).Result()
+	if err != nil || len(r.transformers) == 0 {
+		return reply, err
+	}
+	for i, v := range reply {
+		if reply[i], err = r.decodeReply(key, fields[i], v); err != nil {
+			return nil, err
+		}
+	}
+	return reply, nil
 }
 
 // ZCard 获取有序集合的基数
 func (r *Component) ZCard(ctx context.Context, key string) (int64, error) {
-	return r.client.ZCard(ctx, key).Result()
+	cmd := r.client.ZCard(ctx, key)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // ZScore 获取有序集合成员 member 的 score 值
 func (r *Component) ZScore(ctx context.Context, key string, member string) (float64, error) {
-	return r.client.ZScore(ctx, key, member).Result()
+	cmd := r.client.ZScore(ctx, key, member)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // ZAdd 将一个或多个 member 元素及其 score 值加入到有序集 key 当中
 func (r *Component) ZAdd(ctx context.Context, key string, members ...redis.Z) (int64, error) {
-	return r.client.ZAdd(ctx, key, members...).Result()
+	cmd := r.client.ZAdd(ctx, key, members...)
+	return cmd.Val(), r.cmdErr(cmd)
+}
+
+// ZIncrBy 为有序集 key 的成员 member 的 score 值加上增量 increment
+func (r *Component) ZIncrBy(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	cmd := r.client.ZIncrBy(ctx, key, increment, member)
+	return cmd.Val(), r.cmdErr(cmd)
+}
+
+// ZUnionStore 计算给定的一个或多个有序集的并集，并将结果储存到 dest
+func (r *Component) ZUnionStore(ctx context.Context, dest string, store *redis.ZStore) (int64, error) {
+	cmd := r.client.ZUnionStore(ctx, dest, store)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // ZCount 返回有序集 key 中， score 值在 min 和 max 之间(默认包括 score 值等于 min 或 max )的成员的数量。
 func (r *Component) ZCount(ctx context.Context, key string, min, max string) (int64, error) {
-	return r.client.ZCount(ctx, key, min, max).Result()
+	cmd := r.client.ZCount(ctx, key, min, max)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // Del redis删除
 func (r *Component) Del(ctx context.Context, key ...string) (int64, error) {
-	return r.client.Del(ctx, key...).Result()
+	reply, err := r.del(ctx, key)
+	return reply, r.wrapErr("del", err)
 }
 
 // HIncrBy 哈希field自增
 func (r *Component) HIncrBy(ctx context.Context, key string, field string, incr int) (int64, error) {
-	return r.client.HIncrBy(ctx, key, field, int64(incr)).Result()
+	cmd := r.client.HIncrBy(ctx, key, field, int64(incr))
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // Exists 键是否存在
 func (r *Component) Exists(ctx context.Context, key string) (bool, error) {
-	result, err := r.client.Exists(ctx, key).Result()
-	if err != nil {
-		return result == 1, err
-	}
-	return result == 1, nil
+	cmd := r.client.Exists(ctx, key)
+	return cmd.Val() == 1, r.cmdErr(cmd)
 }
 
 // LPush 将一个或多个值 value 插入到列表 key 的表头
 func (r *Component) LPush(ctx context.Context, key string, values ...interface{}) (int64, error) {
-	return r.client.LPush(ctx, key, values...).Result()
+	cmd := r.client.LPush(ctx, key, values...)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // RPush 将一个或多个值 value 插入到列表 key 的表尾(最右边)。
 func (r *Component) RPush(ctx context.Context, key string, values ...interface{}) (int64, error) {
-	return r.client.RPush(ctx, key, values...).Result()
+	cmd := r.client.RPush(ctx, key, values...)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // RPop 移除并返回列表 key 的尾元素。
 func (r *Component) RPop(ctx context.Context, key string) (string, error) {
-	return r.client.RPop(ctx, key).Result()
+	cmd := r.client.RPop(ctx, key)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // LRange 获取列表指定范围内的元素
 func (r *Component) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
-	return r.client.LRange(ctx, key, start, stop).Result()
+	cmd := r.client.LRange(ctx, key, start, stop)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // LLen ...
 func (r *Component) LLen(ctx context.Context, key string) (int64, error) {
-	return r.client.LLen(ctx, key).Result()
+	cmd := r.client.LLen(ctx, key)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // LRem ...
 func (r *Component) LRem(ctx context.Context, key string, count int64, value interface{}) (int64, error) {
-	return r.client.LRem(ctx, key, count, value).Result()
+	cmd := r.client.LRem(ctx, key, count, value)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // LIndex ...
 func (r *Component) LIndex(ctx context.Context, key string, idx int64) (string, error) {
-	return r.client.LIndex(ctx, key, idx).Result()
+	cmd := r.client.LIndex(ctx, key, idx)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // LTrim ...
 func (r *Component) LTrim(ctx context.Context, key string, start, stop int64) (string, error) {
-	return r.client.LTrim(ctx, key, start, stop).Result()
+	cmd := r.client.LTrim(ctx, key, start, stop)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // ZRemRangeByRank 移除有序集合中给定的排名区间的所有成员
 func (r *Component) ZRemRangeByRank(ctx context.Context, key string, start, stop int64) (int64, error) {
-	return r.client.ZRemRangeByRank(ctx, key, start, stop).Result()
+	cmd := r.client.ZRemRangeByRank(ctx, key, start, stop)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // Expire 设置过期时间
 func (r *Component) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
-	return r.client.Expire(ctx, key, expiration).Result()
+	cmd := r.client.Expire(ctx, key, expiration)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // ZRem 从zset中移除变量
 func (r *Component) ZRem(ctx context.Context, key string, members ...interface{}) (int64, error) {
-	return r.client.ZRem(ctx, key, members...).Result()
+	cmd := r.client.ZRem(ctx, key, members...)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // SAdd 向set中添加成员
 func (r *Component) SAdd(ctx context.Context, key string, member ...interface{}) (int64, error) {
-	return r.client.SAdd(ctx, key, member...).Result()
+	cmd := r.client.SAdd(ctx, key, member...)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // SMembers 返回set的全部成员
 func (r *Component) SMembers(ctx context.Context, key string) ([]string, error) {
-	return r.client.SMembers(ctx, key).Result()
+	cmd := r.client.SMembers(ctx, key)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // SIsMember ...
 func (r *Component) SIsMember(ctx context.Context, key string, member interface{}) (bool, error) {
-	return r.client.SIsMember(ctx, key, member).Result()
+	cmd := r.client.SIsMember(ctx, key, member)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // SCard 获取集合内的元素个数
 func (r *Component) SCard(ctx context.Context, key string) (int64, error) {
-	return r.client.SCard(ctx, key).Result()
+	cmd := r.client.SCard(ctx, key)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // SRem ...
 func (r *Component) SRem(ctx context.Context, key string, member interface{}) (int64, error) {
-	return r.client.SRem(ctx, key, member).Result()
+	cmd := r.client.SRem(ctx, key, member)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // HKeys 获取hash的所有域
 func (r *Component) HKeys(ctx context.Context, key string) ([]string, error) {
-	return r.client.HKeys(ctx, key).Result()
+	cmd := r.client.HKeys(ctx, key)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // HLen 获取hash的长度
 func (r *Component) HLen(ctx context.Context, key string) (int64, error) {
-	return r.client.HLen(ctx, key).Result()
+	cmd := r.client.HLen(ctx, key)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // GeoAdd 写入地理位置
 func (r *Component) GeoAdd(ctx context.Context, key string, location *redis.GeoLocation) (int64, error) {
-	return r.client.GeoAdd(ctx, key, location).Result()
+	cmd := r.client.GeoAdd(ctx, key, location)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // GeoRadius 根据经纬度查询列表
+//
+// Deprecated: GEORADIUS 自 Redis 6.2 起已废弃，使用 GeoSearch、GeoSearchLocation 代替。
 func (r *Component) GeoRadius(ctx context.Context, key string, longitude, latitude float64, query *redis.GeoRadiusQuery) ([]redis.GeoLocation, error) {
-	return r.client.GeoRadius(ctx, key, longitude, latitude, query).Result()
+	cmd := r.client.GeoRadius(ctx, key, longitude, latitude, query)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // TTL 查询过期时间
 func (r *Component) TTL(ctx context.Context, key string) (time.Duration, error) {
-	return r.client.TTL(ctx, key).Result()
+	cmd := r.client.TTL(ctx, key)
+	return cmd.Val(), r.cmdErr(cmd)
 }
 
 // Close closes the cluster client, releasing any open resources.
@@ -388,17 +503,11 @@ func (r *Component) Close() (err error) {
 	err = nil
 	if r.client != nil {
 		if r.Cluster() != nil {
-			err = r.Cluster().Close()
-			if err != nil {
-				err = fmt.Errorf("cluster close err %w", err)
-			}
+			err = r.wrapErr("close", r.Cluster().Close())
 		}
 
 		if r.Stub() != nil {
-			err = r.Stub().Close()
-			if err != nil {
-				err = fmt.Errorf("stub close err %w", err)
-			}
+			err = r.wrapErr("close", r.Stub().Close())
 		}
 	}
 	return err
@@ -9,10 +9,14 @@ const PackageName = "component.eredis"
 
 // Component client (cmdable and config)
 type Component struct {
-	config     *config
-	client     redis.Cmdable
-	lockClient *lockClient
-	logger     *elog.Component
+	name         string
+	config       *config
+	client       redis.Cmdable
+	lockClient   *lockClient
+	codec        Codec
+	transformers []valueTransformer
+	hotKeys      *hotKeyDetector
+	logger       *elog.Component
 }
 
 // Client returns a universal redis client(ClusterClient, StubClient or SentinelClient), it depends on you config.
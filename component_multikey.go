@@ -0,0 +1,152 @@
+package eredis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// multi-key 命令在 cluster 模式下要求所有 key 位于同一个 slot，否则会返回 CROSSSLOT 错误。
+// 以下方法在 cluster 模式下先按 slot 对 key 分组，再通过 pipeline 下发，
+// ClusterClient 的 pipeline 会按节点并行执行，最后按入参顺序组装结果。
+// stub/sentinel 模式下保持单条命令的行为不变。
+
+// crossSlot 是否需要按 slot 拆分命令
+func (r *Component) crossSlot(keys []string) bool {
+	return r.Cluster() != nil && len(keys) > 1
+}
+
+// mget 按 slot 拆分 MGET，结果顺序与 keys 一致
+func (r *Component) mget(ctx context.Context, keys []string) ([]interface{}, error) {
+	if !r.crossSlot(keys) {
+		return r.client.MGet(ctx, keys...).Result()
+	}
+	groups := groupKeysBySlot(r.config.KeyPrefix, keys)
+	if len(groups) == 1 {
+		return r.client.MGet(ctx, keys...).Result()
+	}
+
+	cmds := make([]*redis.SliceCmd, len(groups))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, group := range groups {
+			cmds[i] = pipe.MGet(ctx, group.keys...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reply := make([]interface{}, len(keys))
+	for i, group := range groups {
+		for j, v := range cmds[i].Val() {
+			reply[group.idx[j]] = v
+		}
+	}
+	return reply, nil
+}
+
+// del 按 slot 拆分 DEL，返回删除的 key 总数
+func (r *Component) del(ctx context.Context, keys []string) (int64, error) {
+	return r.sumBySlot(ctx, keys, func(c redis.Cmdable, keys []string) *redis.IntCmd {
+		return c.Del(ctx, keys...)
+	})
+}
+
+// unlink 按 slot 拆分 UNLINK，返回删除的 key 总数
+func (r *Component) unlink(ctx context.Context, keys []string) (int64, error) {
+	return r.sumBySlot(ctx, keys, func(c redis.Cmdable, keys []string) *redis.IntCmd {
+		return c.Unlink(ctx, keys...)
+	})
+}
+
+// sumBySlot 按 slot 拆分返回整数的 multi-key 命令，并对各分组的结果求和
+func (r *Component) sumBySlot(ctx context.Context, keys []string, fn func(c redis.Cmdable, keys []string) *redis.IntCmd) (int64, error) {
+	if !r.crossSlot(keys) {
+		return fn(r.client, keys).Result()
+	}
+	groups := groupKeysBySlot(r.config.KeyPrefix, keys)
+	if len(groups) == 1 {
+		return fn(r.client, keys).Result()
+	}
+
+	cmds := make([]*redis.IntCmd, len(groups))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, group := range groups {
+			cmds[i] = fn(pipe, group.keys)
+		}
+		return nil
+	})
+
+	var total int64
+	for _, cmd := range cmds {
+		total += cmd.Val()
+	}
+	return total, err
+}
+
+// MSet 批量设置 string，cluster 模式下按 slot 拆分为多条 MSET 并行执行。
+// 注意：跨 slot 时整体不再是原子操作，部分分组可能已经写入成功。
+func (r *Component) MSet(ctx context.Context, values map[string]interface{}) error {
+	if len(values) == 0 {
+		return r.wrapErr("mset", ErrInvalidParams)
+	}
+	values, err := r.encodeValues(values)
+	if err != nil {
+		return r.wrapErr("mset", err)
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	if !r.crossSlot(keys) {
+		return r.cmdErr(r.client.MSet(ctx, values))
+	}
+	groups := groupKeysBySlot(r.config.KeyPrefix, keys)
+	if len(groups) == 1 {
+		return r.cmdErr(r.client.MSet(ctx, values))
+	}
+
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, group := range groups {
+			pairs := make([]interface{}, 0, 2*len(group.keys))
+			for _, key := range group.keys {
+				pairs = append(pairs, key, values[key])
+			}
+			pipe.MSet(ctx, pairs...)
+		}
+		return nil
+	})
+	return r.wrapErr("mset", err)
+}
+
+// MSetWithTTL 批量设置 string 并指定过期时间。
+// MSET 不支持过期时间，因此下发多条 SET key value PX ttl：stub/sentinel 模式下使用 MULTI/EXEC 保证原子性，
+// cluster 模式下通过 pipeline 按节点并行执行。
+func (r *Component) MSetWithTTL(ctx context.Context, values map[string]interface{}, expire time.Duration) error {
+	if len(values) == 0 {
+		return r.wrapErr("mset", ErrInvalidParams)
+	}
+	if expire <= 0 {
+		return r.MSet(ctx, values)
+	}
+	values, err := r.encodeValues(values)
+	if err != nil {
+		return r.wrapErr("mset", err)
+	}
+
+	fn := func(pipe redis.Pipeliner) error {
+		for key, value := range values {
+			pipe.Set(ctx, key, value, expire)
+		}
+		return nil
+	}
+	if r.Cluster() == nil {
+		_, err = r.client.TxPipelined(ctx, fn)
+		return r.wrapErr("mset", err)
+	}
+	_, err = r.client.Pipelined(ctx, fn)
+	return r.wrapErr("mset", err)
+}
@@ -0,0 +1,80 @@
+package eredis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiKeyCrossSlot(t *testing.T) {
+	recorder := &commandRecorder{}
+	comp := newTestCluster(t, "redis.multiKeyClusterTest", withInterceptor(recorder))
+	ctx := context.Background()
+	base := newTestKey(t, comp, "multikey")
+	a1, b1, a2, c1, b2 := "{"+base+":a}1", "{"+base+":b}1", "{"+base+":a}2", "{"+base+":c}1", "{"+base+":b}2"
+	keys := []string{a1, b1, a2, c1, b2}
+	t.Cleanup(func() { _, _ = comp.Del(ctx, keys...) })
+	assert.Len(t, groupKeysBySlot("", keys), 3)
+
+	// 按 slot 拆分为多条 MSET，由 pipeline 下发
+	assert.NoError(t, comp.MSet(ctx, map[string]interface{}{a1: "a1", b1: "b1", a2: "a2", c1: "c1"}))
+	assert.Equal(t, 3, recorder.count("mset"))
+
+	// 各分组的 MGET 结果按入参顺序组装，不存在的 key 返回 nil
+	values, err := comp.MGet(ctx, b2, a1, c1, b1, a2)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{nil, "a1", "c1", "b1", "a2"}, values)
+	assert.Equal(t, 3, recorder.count("mget"))
+	strs, err := comp.MGetString(ctx, a2, b2, b1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a2", "", "b1"}, strs)
+
+	// 同一个 slot 的 key 使用单条命令
+	values, err = comp.MGet(ctx, a1, a2)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"a1", "a2"}, values)
+
+	// DEL、UNLINK 对各分组删除的数量求和
+	n, err := comp.Del(ctx, a1, b1, b2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	n, err = comp.unlink(ctx, []string{a2, c1, b2})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	// cluster 模式下 MSetWithTTL 通过 pipeline 下发，不使用 MULTI/EXEC
+	assert.NoError(t, comp.MSetWithTTL(ctx, map[string]interface{}{a1: "a1", b1: "b1", c1: "c1"}, time.Minute))
+	assert.Equal(t, 0, recorder.count("multi"))
+	for _, key := range []string{a1, b1, c1} {
+		ttl, err := comp.TTL(ctx, key)
+		assert.NoError(t, err)
+		assert.Greater(t, ttl, time.Duration(0))
+	}
+}
+
+func TestMSetWithTTL(t *testing.T) {
+	recorder := &commandRecorder{}
+	comp := newTestRedis(t, "redis.multiKeyTest", withInterceptor(recorder))
+	ctx := context.Background()
+	k1, k2 := newTestKey(t, comp, "mset"), newTestKey(t, comp, "mset")
+
+	// stub 模式下使用 MULTI/EXEC 保证原子性
+	assert.NoError(t, comp.MSetWithTTL(ctx, map[string]interface{}{k1: "v1", k2: "v2"}, time.Minute))
+	assert.Equal(t, 1, recorder.count("multi"))
+	values, err := comp.MGet(ctx, k1, k2)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"v1", "v2"}, values)
+	ttl, err := comp.TTL(ctx, k2)
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+
+	// 参数错误时返回 mset 的 *Error
+	err = comp.MSetWithTTL(ctx, nil, time.Minute)
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "mset", e.Cmd)
+	assert.ErrorIs(t, err, ErrInvalidParams)
+}
@@ -2,11 +2,16 @@ package eredis
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/gotomicro/ego/core/econf"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,3 +35,110 @@ func TestSentinel(t *testing.T) {
 	assert.NoError(t, err)
 	t.Log("ping result", res)
 }
+
+// newTestRedis 连接 EREDIS_TEST_ADDR（默认 127.0.0.1:6379）指定的 redis，无法连接时跳过测试
+func newTestRedis(t *testing.T, name string, options ...Option) *Component {
+	addr := os.Getenv("EREDIS_TEST_ADDR")
+	if addr == "" {
+		addr = "127.0.0.1:6379"
+	}
+	conf := fmt.Sprintf(`
+[%s]
+	addr = "%s"
+	dialTimeout = "200ms"
+	maxRetries = -1
+	onFail = "error"
+`, name, addr)
+	if err := econf.LoadFromReader(strings.NewReader(conf), toml.Unmarshal); err != nil {
+		t.Fatal(err)
+	}
+	cmp := Load(name).Build(options...)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := cmp.Ping(ctx); err != nil {
+		_ = cmp.Close()
+		t.Skipf("redis %s unavailable: %v", addr, err)
+	}
+	t.Cleanup(func() { _ = cmp.Close() })
+	return cmp
+}
+
+// newTestCluster 以 cluster 模式连接测试使用的 Redis，不支持 CLUSTER SLOTS 时跳过测试。
+// miniredis 以及开启了 cluster 的单节点 Redis 负责所有的 slot
+func newTestCluster(t *testing.T, name string, options ...Option) *Component {
+	addr := os.Getenv("EREDIS_TEST_CLUSTER_ADDR")
+	if addr == "" {
+		addr = "127.0.0.1:6379"
+	}
+	conf := fmt.Sprintf(`
+[%s]
+	mode = "cluster"
+	addrs = ["%s"]
+	dialTimeout = "200ms"
+	maxRetries = -1
+	onFail = "error"
+`, name, addr)
+	if err := econf.LoadFromReader(strings.NewReader(conf), toml.Unmarshal); err != nil {
+		t.Fatal(err)
+	}
+	cmp := Load(name).Build(options...)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := cmp.Ping(ctx); err != nil {
+		_ = cmp.Close()
+		t.Skipf("redis cluster %s unavailable: %v", addr, err)
+	}
+	t.Cleanup(func() { _ = cmp.Close() })
+	return cmp
+}
+
+// newTestKey 返回测试使用的唯一 key，测试结束后删除
+func newTestKey(t *testing.T, cmp *Component, prefix string) string {
+	token, err := randomToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "eredis:test:" + prefix + ":" + token
+	t.Cleanup(func() { _, _ = cmp.Del(context.Background(), key) })
+	return key
+}
+
+// commandRecorder 记录经过拦截器的命令，包括 pipeline 中的命令
+type commandRecorder struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (c *commandRecorder) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (c *commandRecorder) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		c.mu.Lock()
+		c.names = append(c.names, cmd.Name())
+		c.mu.Unlock()
+		return next(ctx, cmd)
+	}
+}
+
+func (c *commandRecorder) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		c.mu.Lock()
+		for _, cmd := range cmds {
+			c.names = append(c.names, cmd.Name())
+		}
+		c.mu.Unlock()
+		return next(ctx, cmds)
+	}
+}
+
+func (c *commandRecorder) count(name string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, v := range c.names {
+		if v == name {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,152 @@
+package eredis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/klauspost/compress/zstd"
+)
+
+// 压缩算法
+const (
+	CompressSnappy = "snappy"
+	CompressZstd   = "zstd"
+	CompressGzip   = "gzip"
+)
+
+// compressMagic 压缩值的头部，0xfe 不会出现在合法的 UTF-8 文本中，第 4 个字节为算法编号
+var compressMagic = []byte{0xfe, 'e', 'z'}
+
+var compressIDs = map[string]byte{
+	CompressSnappy: 1,
+	CompressZstd:   2,
+	CompressGzip:   3,
+}
+
+var compressRatioHistogram = emetric.HistogramVecOpts{
+	Namespace: emetric.DefaultNamespace,
+	Name:      "client_redis_compression_ratio",
+	Help:      "compressed size / original size of redis values",
+	Labels:    []string{"name", "algorithm"},
+	Buckets:   []float64{0.05, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1},
+}.Build()
+
+// CompressionConfig 值压缩配置，对 Set、Get、HSet、HGet 等 string、hash 方法透明生效，通过 Client() 调用时不生效
+type CompressionConfig struct {
+	Algorithm string // Algorithm 压缩算法 snappy|zstd|gzip，为空时不压缩
+	Threshold int    // Threshold 值超过该字节数时才压缩，默认 1024
+}
+
+// DefaultCompressionConfig 默认压缩配置
+func DefaultCompressionConfig() CompressionConfig {
+	return CompressionConfig{
+		Threshold: 1024,
+	}
+}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+func initZstd() {
+	zstdOnce.Do(func() {
+		zstdEncoder, _ = zstd.NewWriter(nil)
+		zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	})
+}
+
+// compressor 压缩值，读取时根据头部的算法编号解压，因此修改算法后仍然可以读取旧值
+type compressor struct {
+	compName  string
+	algorithm string
+	threshold int
+}
+
+func newCompressor(compName string, config CompressionConfig) (*compressor, error) {
+	if _, ok := compressIDs[config.Algorithm]; !ok {
+		return nil, fmt.Errorf("unknown compression algorithm %q", config.Algorithm)
+	}
+	if config.Algorithm == CompressZstd {
+		initZstd()
+	}
+	return &compressor{compName: compName, algorithm: config.Algorithm, threshold: config.Threshold}, nil
+}
+
+func (c *compressor) name() string {
+	return c.algorithm
+}
+
+func (c *compressor) encode(key, field string, data []byte) ([]byte, error) {
+	if len(data) < c.threshold {
+		return data, nil
+	}
+	out, err := compress(c.algorithm, data)
+	if err != nil {
+		return nil, err
+	}
+	compressRatioHistogram.Observe(float64(len(out))/float64(len(data)), c.compName, c.algorithm)
+	// 压缩后没有变小时保存原值
+	if len(out)+len(compressMagic)+1 >= len(data) {
+		return data, nil
+	}
+	buf := make([]byte, 0, len(compressMagic)+1+len(out))
+	buf = append(buf, compressMagic...)
+	buf = append(buf, compressIDs[c.algorithm])
+	return append(buf, out...), nil
+}
+
+func (c *compressor) decode(key, field string, data []byte) ([]byte, error) {
+	if len(data) <= len(compressMagic) || !bytes.HasPrefix(data, compressMagic) {
+		return data, nil
+	}
+	id, body := data[len(compressMagic)], data[len(compressMagic)+1:]
+	for algorithm, v := range compressIDs {
+		if v == id {
+			return decompress(algorithm, body)
+		}
+	}
+	return nil, fmt.Errorf("unknown compression id %d", id)
+}
+
+func compress(algorithm string, data []byte) ([]byte, error) {
+	switch algorithm {
+	case CompressSnappy:
+		return snappy.Encode(nil, data), nil
+	case CompressZstd:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	default:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+func decompress(algorithm string, data []byte) ([]byte, error) {
+	switch algorithm {
+	case CompressSnappy:
+		return snappy.Decode(nil, data)
+	case CompressZstd:
+		initZstd()
+		return zstdDecoder.DecodeAll(data, nil)
+	default:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+}
@@ -0,0 +1,70 @@
+package eredis
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressor(t *testing.T) {
+	data := []byte(strings.Repeat(`{"name":"ego","age":3}`, 100))
+	for _, algorithm := range []string{CompressSnappy, CompressZstd, CompressGzip} {
+		c, err := newCompressor("test", CompressionConfig{Algorithm: algorithm, Threshold: 1024})
+		assert.NoError(t, err)
+
+		out, err := c.encode("key", "", data)
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(out, compressMagic), algorithm)
+		assert.Less(t, len(out), len(data))
+
+		raw, err := c.decode("key", "", out)
+		assert.NoError(t, err)
+		assert.Equal(t, data, raw)
+
+		// 小于门限值的值不压缩
+		out, err = c.encode("key", "", []byte("small"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("small"), out)
+	}
+
+	// 修改算法后仍然可以读取旧值
+	snappyC, _ := newCompressor("test", CompressionConfig{Algorithm: CompressSnappy})
+	gzipC, _ := newCompressor("test", CompressionConfig{Algorithm: CompressGzip})
+	out, _ := snappyC.encode("key", "", data)
+	raw, err := gzipC.decode("key", "", out)
+	assert.NoError(t, err)
+	assert.Equal(t, data, raw)
+
+	_, err = newCompressor("test", CompressionConfig{Algorithm: "lz4"})
+	assert.Error(t, err)
+}
+
+func TestTransformers(t *testing.T) {
+	c, _ := newCompressor("test", CompressionConfig{Algorithm: CompressZstd, Threshold: 16})
+	r := &Component{transformers: []valueTransformer{c}}
+
+	value := strings.Repeat("a", 100)
+	v, err := r.encodeRaw("key", "", value)
+	assert.NoError(t, err)
+	s, err := r.decodeRaw("key", "", string(v.([]byte)))
+	assert.NoError(t, err)
+	assert.Equal(t, value, s)
+
+	// 未压缩的旧值原样返回
+	s, err = r.decodeRaw("key", "", "plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", s)
+
+	v, err = r.encodeRaw("key", "", 42)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("42"), v)
+
+	_, err = r.decodeRaw("key", "field", string(compressMagic)+"\x02broken")
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "field", de.Field)
+	assert.Equal(t, CompressZstd, de.Codec)
+}
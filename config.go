@@ -19,32 +19,49 @@ const (
 
 // config for redis, contains RedisStubConfig, RedisClusterConfig and RedisSentinelConfig
 type config struct {
-	Addrs                      []string       // Addrs cluster|sentinel 模式下实例配置地址
-	Addr                       string         // Addr stub 模式下实例配置地址
-	Mode                       string         // Mode Redis模式 cluster|stub|sentinel
-	MasterName                 string         // MasterName 哨兵主节点名称，sentinel模式下需要配置此项
-	SentinelUsername           string         // SentinelUsername sentinel 模式下用户密码
-	SentinelPassword           string         // SentinelPassword sentinel 模式下密码
-	Password                   string         // Password cluster|stub 模式下密码
-	DB                         int            // DB，默认为0, 一般应用不推荐使用DB分片
-	PoolSize                   int            // PoolSize 集群内每个节点的最大连接池限制
-	MaxRetries                 int            // MaxRetries 网络相关的错误最大重试次数 默认8次
-	MinIdleConns               int            // MinIdleConns 最小空闲连接数
-	DialTimeout                time.Duration  // DialTimeout 拨超时时间
-	ReadTimeout                time.Duration  // ReadTimeout 读超时 默认3s
-	WriteTimeout               time.Duration  // WriteTimeout 读超时 默认3s
-	IdleTimeout                time.Duration  // IdleTimeout 连接最大空闲时间，默认60s, 超过该时间，连接会被主动关闭
-	Debug                      bool           // Debug 开关， 是否开启调试，默认不开启，开启后并加上export EGO_DEBUG=true，可以看到每次请求，配置名、地址、耗时、请求数据、响应数据
-	ReadOnly                   bool           // ReadOnly 集群模式 在从属节点上启用读模式
-	SlowLogThreshold           time.Duration  // SlowLogThreshold 慢日志门限值，超过该门限值的请求，将被记录到慢日志中
-	OnFail                     string         // OnFail panic|error
-	EnableMetricInterceptor    bool           // EnableMetricInterceptor 是否开启监控，默认开启
-	EnableTraceInterceptor     bool           // EnableTraceInterceptor 是否开启链路，默认
-	EnableAccessInterceptor    bool           // EnableAccessInterceptor 是否开启，记录请求数据
-	EnableAccessInterceptorReq bool           // EnableAccessInterceptorReq 是否开启记录请求参数
-	EnableAccessInterceptorRes bool           // EnableAccessInterceptorRes 是否开启记录响应参数
-	Authentication             Authentication // Authentication TLS 参数支持
+	Addrs                      []string          // Addrs cluster|sentinel 模式下实例配置地址
+	Addr                       string            // Addr stub 模式下实例配置地址
+	Mode                       string            // Mode Redis模式 cluster|stub|sentinel
+	MasterName                 string            // MasterName 哨兵主节点名称，sentinel模式下需要配置此项
+	SentinelUsername           string            // SentinelUsername sentinel 模式下用户密码
+	SentinelPassword           string            // SentinelPassword sentinel 模式下密码
+	Password                   string            // Password cluster|stub 模式下密码
+	DB                         int               // DB，默认为0, 一般应用不推荐使用DB分片
+	PoolSize                   int               // PoolSize 集群内每个节点的最大连接池限制
+	MaxRetries                 int               // MaxRetries 网络相关的错误最大重试次数 默认8次
+	MinIdleConns               int               // MinIdleConns 最小空闲连接数
+	DialTimeout                time.Duration     // DialTimeout 拨超时时间
+	ReadTimeout                time.Duration     // ReadTimeout 读超时 默认3s
+	WriteTimeout               time.Duration     // WriteTimeout 读超时 默认3s
+	IdleTimeout                time.Duration     // IdleTimeout 连接最大空闲时间，默认60s, 超过该时间，连接会被主动关闭
+	Debug                      bool              // Debug 开关， 是否开启调试，默认不开启，开启后并加上export EGO_DEBUG=true，可以看到每次请求，配置名、地址、耗时、请求数据、响应数据
+	ReadOnly                   bool              // ReadOnly 集群模式 在从属节点上启用读模式
+	SlowLogThreshold           time.Duration     // SlowLogThreshold 慢日志门限值，超过该门限值的请求，将被记录到慢日志中
+	OnFail                     string            // OnFail panic|error
+	EnableMetricInterceptor    bool              // EnableMetricInterceptor 是否开启监控，默认开启
+	EnableTraceInterceptor     bool              // EnableTraceInterceptor 是否开启链路，默认
+	EnableAccessInterceptor    bool              // EnableAccessInterceptor 是否开启，记录请求数据
+	EnableAccessInterceptorReq bool              // EnableAccessInterceptorReq 是否开启记录请求参数
+	EnableAccessInterceptorRes bool              // EnableAccessInterceptorRes 是否开启记录响应参数
+	EnableBreakerInterceptor   bool              // EnableBreakerInterceptor 是否开启熔断，默认不开启
+	Breaker                    BreakerConfig     // Breaker 熔断配置
+	EnableThrottleInterceptor  bool              // EnableThrottleInterceptor 是否开启客户端命令限流，默认不开启
+	Throttle                   ThrottleConfig    // Throttle 客户端命令限流配置
+	EnableGuardInterceptor     bool              // EnableGuardInterceptor 是否开启危险命令守卫，默认不开启
+	Guard                      GuardConfig       // Guard 危险命令守卫配置
+	EnablePolicyInterceptor    bool              // EnablePolicyInterceptor 是否开启写入策略检查（value 大小、过期时间），默认不开启
+	Policy                     PolicyConfig      // Policy 写入策略配置
+	EnableHotKeyInterceptor    bool              // EnableHotKeyInterceptor 是否开启热 key 统计，默认不开启
+	EnableTracePropagation     bool              // EnableTracePropagation 是否在 stream、PUBLISH 消息中携带 trace context，默认不开启，开启之前需要先升级订阅方
+	HotKey                     HotKeyConfig      // HotKey 热 key 统计配置
+	Compression                CompressionConfig // Compression 值压缩配置
+	Encryption                 EncryptionConfig  // Encryption 值加密配置
+	KeyPrefix                  string            // KeyPrefix 所有 key 的全局前缀，多个服务共用一个 redis 时用于隔离，SCAN、KEYS 返回的 key 会去掉前缀
+	Codec                      string            // Codec GetValue、SetValue 等泛型方法使用的编解码器 json|msgpack|proto，默认 json
+	Authentication             Authentication    // Authentication TLS 参数支持
 	interceptors               []redis.Hook
+	codec                      Codec
+	keyLoader                  KeyLoader
 }
 
 // DefaultConfig default config ...
@@ -65,6 +82,13 @@ func DefaultConfig() *config {
 		EnableTraceInterceptor:  true,
 		SlowLogThreshold:        xtime.Duration("250ms"),
 		OnFail:                  "panic",
+		Breaker:                 DefaultBreakerConfig(),
+		Throttle:                DefaultThrottleConfig(),
+		Guard:                   DefaultGuardConfig(),
+		Policy:                  DefaultPolicyConfig(),
+		HotKey:                  DefaultHotKeyConfig(),
+		Codec:                   CodecJSON,
+		Compression:             DefaultCompressionConfig(),
 	}
 }
 
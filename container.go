@@ -41,6 +41,25 @@ func Load(key string) *Container {
 // Build 构建Component
 func (c *Container) Build(options ...Option) *Component {
 	options = append(options, withInterceptor(fixedInterceptor(c.name, c.config, c.logger)))
+	if c.config.EnableGuardInterceptor {
+		options = append(options, withInterceptor(guardInterceptor(c.name, c.config, c.logger)))
+	}
+	// 写入策略在 key 前缀之前检查，TTLPrefixes 使用业务代码中的 key
+	var writePolicy *policy
+	if c.config.EnablePolicyInterceptor {
+		writePolicy = newPolicy(c.name, c.config.Policy, c.logger)
+		options = append(options, withInterceptor(policyInterceptor(writePolicy)))
+	}
+	locator := &keyLocator{logger: c.logger}
+	var hotKeys *hotKeyDetector
+	if c.config.EnableHotKeyInterceptor {
+		hotKeys = newHotKeyDetector(c.name, c.config.HotKey, locator)
+		hotKeyDetectors.Store(c.name, hotKeys)
+		options = append(options, withInterceptor(hotKeyInterceptor(hotKeys)))
+	}
+	if c.config.KeyPrefix != "" {
+		options = append(options, withInterceptor(keyPrefixInterceptor(&keyPrefixer{prefix: c.config.KeyPrefix, keyLocator: locator})))
+	}
 	if c.config.Debug {
 		options = append(options, withInterceptor(debugInterceptor(c.name, c.config, c.logger)))
 	}
@@ -53,6 +72,12 @@ func (c *Container) Build(options ...Option) *Component {
 	if c.config.EnableTraceInterceptor {
 		options = append(options, withInterceptor(traceInterceptor(c.name, c.config, c.logger)))
 	}
+	if c.config.EnableThrottleInterceptor {
+		options = append(options, withInterceptor(throttleInterceptor(c.name, c.config, c.logger)))
+	}
+	if c.config.EnableBreakerInterceptor && !(c.config.Mode == ClusterMode && c.config.Breaker.PerNode) {
+		options = append(options, withInterceptor(breakerInterceptor(newBreaker(c.name, c.config.AddrString(), c.config.Breaker, c.logger))))
+	}
 	for _, option := range options {
 		option(c)
 	}
@@ -98,13 +123,47 @@ func (c *Container) Build(options ...Option) *Component {
 	}
 
 	c.logger = c.logger.With(elog.FieldAddr(fmt.Sprintf("%s", c.config.Addrs)))
+	locator.setClient(client)
+	if writePolicy != nil {
+		writePolicy.client = client
+	}
+
+	codec := c.config.codec
+	if codec == nil {
+		var ok bool
+		if codec, ok = GetCodec(c.config.Codec); !ok {
+			c.logger.Panic(`invalid "codec" config, codec not registered`, elog.FieldValue(c.config.Codec))
+		}
+	}
+	var transformers []valueTransformer
+	if c.config.Compression.Algorithm != "" {
+		compressor, err := newCompressor(c.name, c.config.Compression)
+		if err != nil {
+			c.logger.Panic(`invalid "compression" config`, elog.FieldErr(err))
+		}
+		transformers = append(transformers, compressor)
+	}
+	// 先压缩再加密，密文无法压缩
+	if c.config.Encryption.KeyFile != "" || c.config.keyLoader != nil {
+		encryptor, err := newEncryptor(c.config.Encryption, c.config.keyLoader, c.logger)
+		if err != nil {
+			c.logger.Panic(`invalid "encryption" config`, elog.FieldErr(err))
+		}
+		transformers = append(transformers, encryptor)
+	}
 
-	return &Component{
-		config:     c.config,
-		client:     client,
-		lockClient: &lockClient{client: client},
-		logger:     c.logger,
+	comp := &Component{
+		name:         c.name,
+		config:       c.config,
+		client:       client,
+		lockClient:   &lockClient{client: client},
+		codec:        codec,
+		transformers: transformers,
+		hotKeys:      hotKeys,
+		logger:       c.logger,
 	}
+	components.Store(c.name, comp)
+	return comp
 }
 
 func (c *Container) buildCluster() *redis.ClusterClient {
@@ -126,6 +185,12 @@ func (c *Container) buildCluster() *redis.ClusterClient {
 	for _, incpt := range c.config.interceptors {
 		clusterClient.AddHook(incpt)
 	}
+	// 按节点熔断时，熔断拦截器挂在每个节点的客户端上
+	if c.config.EnableBreakerInterceptor && c.config.Breaker.PerNode {
+		clusterClient.OnNewNode(func(rdb *redis.Client) {
+			rdb.AddHook(breakerInterceptor(newBreaker(c.name, rdb.Options().Addr, c.config.Breaker, c.logger)))
+		})
+	}
 
 	if err := clusterClient.Ping(context.Background()).Err(); err != nil {
 		switch c.config.OnFail {
@@ -0,0 +1,381 @@
+package eredis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/gotomicro/ego/server"
+	"github.com/redis/go-redis/v9"
+)
+
+var queueJobCounter = emetric.CounterVecOpts{
+	Namespace: emetric.DefaultNamespace,
+	Name:      "client_redis_queue_job_total",
+	Help:      "redis queue jobs handled by workers",
+	Labels:    []string{"name", "queue", "result"},
+}.Build()
+
+var (
+	// luaDelayPromote 把到期的任务和超过 visibility timeout 的任务移动到 ready 列表
+	luaDelayPromote = redis.NewScript(`
+local moved = 0
+for i = 1, 2 do
+  local src = KEYS[1]
+  if i == 2 then src = KEYS[3] end
+  local ids = redis.call("zrangebyscore", src, "-inf", ARGV[1], "limit", 0, ARGV[2])
+  for _, id in ipairs(ids) do
+    redis.call("zrem", src, id)
+    redis.call("rpush", KEYS[2], id)
+  end
+  moved = moved + #ids
+end
+return moved`)
+
+	// luaDelayReserve 从 ready 列表取出任务放入 processing，已经取消的任务直接丢弃。
+	// worker 崩溃或者执行超时的任务不会经过 fail，执行次数超过 ARGV[2] + 1 时在这里移动到死信 hash
+	luaDelayReserve = redis.NewScript(`
+local max_retries = tonumber(ARGV[2])
+while true do
+  local id = redis.call("lpop", KEYS[1])
+  if not id then return false end
+  local payload = redis.call("hget", KEYS[3], id)
+  if payload then
+    local attempts = redis.call("hincrby", KEYS[4], id, 1)
+    if max_retries < 0 or attempts <= max_retries + 1 then
+      redis.call("zadd", KEYS[2], ARGV[1], id)
+      return {id, payload, attempts}
+    end
+    redis.call("hdel", KEYS[3], id)
+    redis.call("hdel", KEYS[4], id)
+    redis.call("hset", KEYS[5], id, payload)
+  end
+end`)
+
+	// luaDelayRetry 处理失败的任务延迟重试，任务已经被重新投递或者取消时不做处理
+	luaDelayRetry = redis.NewScript(`
+if redis.call("zrem", KEYS[1], ARGV[1]) == 1 then
+  redis.call("zadd", KEYS[2], ARGV[2], ARGV[1])
+  return 1
+end
+return 0`)
+
+	// luaQueueDead 延迟队列和可靠队列共用，把任务从 processing 中删除，内容移动到死信 hash。
+	// 延迟队列的 processing 为 ZSET，可靠队列为 LIST，任务已经被重新投递或者取消时不做处理
+	luaQueueDead = redis.NewScript(`
+local removed
+if redis.call("type", KEYS[1]).ok == "zset" then
+  removed = redis.call("zrem", KEYS[1], ARGV[1])
+else
+  removed = redis.call("lrem", KEYS[1], 1, ARGV[1])
+end
+if removed == 0 then return 0 end
+local payload = redis.call("hget", KEYS[2], ARGV[1])
+redis.call("hdel", KEYS[2], ARGV[1])
+redis.call("hdel", KEYS[3], ARGV[1])
+if payload then redis.call("hset", KEYS[4], ARGV[1], payload) end
+return 1`)
+)
+
+// Job 队列中的任务
+type Job struct {
+	ID       string
+	Payload  string
+	Attempts int64 // Attempts 执行次数，首次执行为 1
+}
+
+// JobHandler 处理任务，返回 nil 时任务完成，返回错误时按退避时间重试
+type JobHandler func(ctx context.Context, job *Job) error
+
+// DelayQueueOption 延迟队列选项
+type DelayQueueOption func(q *DelayQueue)
+
+// WithDelayQueueWorkers 并发处理任务的 worker 数量，默认 1
+func WithDelayQueueWorkers(workers int) DelayQueueOption {
+	return func(q *DelayQueue) {
+		q.workers = workers
+	}
+}
+
+// WithDelayQueuePollInterval 没有任务时的轮询间隔，也是到期任务的最大延迟，默认 1s
+func WithDelayQueuePollInterval(interval time.Duration) DelayQueueOption {
+	return func(q *DelayQueue) {
+		q.pollInterval = interval
+	}
+}
+
+// WithDelayQueueVisibilityTimeout 任务被取出之后超过该时间没有完成时重新投递，默认 5min
+func WithDelayQueueVisibilityTimeout(timeout time.Duration) DelayQueueOption {
+	return func(q *DelayQueue) {
+		q.visibility = timeout
+	}
+}
+
+// WithDelayQueueMaxRetries 最大重试次数，超过之后移动到死信 hash，默认 3，小于 0 表示不限制
+func WithDelayQueueMaxRetries(retries int64) DelayQueueOption {
+	return func(q *DelayQueue) {
+		q.maxRetries = retries
+	}
+}
+
+// WithDelayQueueBackoff 重试的退避时间，attempts 为已经执行的次数，默认从 1s 开始指数退避，最长 10min
+func WithDelayQueueBackoff(backoff func(attempts int64) time.Duration) DelayQueueOption {
+	return func(q *DelayQueue) {
+		q.backoff = backoff
+	}
+}
+
+// DelayQueue 基于 ZSET 和 LIST 的延迟队列，由 NewDelayQueue 创建。
+// 所有 key 使用 {name} 作为 hash tag，cluster 模式下位于同一个 slot：
+//
+//	{name}:delayed     ZSET 等待执行的任务，score 为执行时间
+//	{name}:ready       LIST 已经到期等待 worker 取出的任务
+//	{name}:processing  ZSET 正在执行的任务，score 为 visibility timeout 到期时间
+//	{name}:jobs        HASH 任务 ID 到内容
+//	{name}:attempts    HASH 任务 ID 到执行次数
+//	{name}:dead        HASH 超过最大重试次数的任务
+type DelayQueue struct {
+	comp         *Component
+	name         string
+	keys         delayQueueKeys
+	workers      int
+	pollInterval time.Duration
+	visibility   time.Duration
+	maxRetries   int64
+	backoff      func(attempts int64) time.Duration
+}
+
+type delayQueueKeys struct {
+	delayed, ready, processing, jobs, attempts, dead string
+}
+
+// NewDelayQueue 创建延迟队列
+func (r *Component) NewDelayQueue(name string, opts ...DelayQueueOption) *DelayQueue {
+	prefix := "{" + name + "}:"
+	q := &DelayQueue{
+		comp: r,
+		name: name,
+		keys: delayQueueKeys{
+			delayed:    prefix + "delayed",
+			ready:      prefix + "ready",
+			processing: prefix + "processing",
+			jobs:       prefix + "jobs",
+			attempts:   prefix + "attempts",
+			dead:       prefix + "dead",
+		},
+		workers:      1,
+		pollInterval: time.Second,
+		visibility:   5 * time.Minute,
+		maxRetries:   3,
+		backoff:      doublingBackoff(time.Second, 10*time.Minute),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if q.workers <= 0 {
+		q.workers = 1
+	}
+	if q.pollInterval <= 0 {
+		q.pollInterval = time.Second
+	}
+	return q
+}
+
+// doublingBackoff 从 base 开始每次翻倍，最长 max
+func doublingBackoff(base, max time.Duration) func(attempts int64) time.Duration {
+	return func(attempts int64) time.Duration {
+		d := base
+		for i := int64(1); i < attempts && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// Enqueue 添加在 runAt 执行的任务，返回任务 ID
+func (q *DelayQueue) Enqueue(ctx context.Context, payload string, runAt time.Time) (string, error) {
+	id, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = q.comp.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, q.keys.jobs, id, payload)
+		pipe.ZAdd(ctx, q.keys.delayed, redis.Z{Score: float64(runAt.UnixMilli()), Member: id})
+		return nil
+	})
+	if err != nil {
+		return "", q.comp.wrapErr("zadd", err)
+	}
+	return id, nil
+}
+
+// Cancel 取消任务，任务不存在或者已经完成时返回 false。正在执行的任务不会被中断，但是失败之后不再重试
+func (q *DelayQueue) Cancel(ctx context.Context, id string) (bool, error) {
+	var del *redis.IntCmd
+	_, err := q.comp.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, q.keys.delayed, id)
+		pipe.LRem(ctx, q.keys.ready, 0, id)
+		pipe.ZRem(ctx, q.keys.processing, id)
+		del = pipe.HDel(ctx, q.keys.jobs, id)
+		pipe.HDel(ctx, q.keys.attempts, id)
+		return nil
+	})
+	if err != nil {
+		return false, q.comp.wrapErr("hdel", err)
+	}
+	return del.Val() > 0, nil
+}
+
+// promote 移动到期的任务，返回移动的数量
+func (q *DelayQueue) promote(ctx context.Context, limit int) (int64, error) {
+	keys := []string{q.keys.delayed, q.keys.ready, q.keys.processing}
+	n, err := luaDelayPromote.Run(ctx, q.comp.client, keys, time.Now().UnixMilli(), limit).Int64()
+	return n, q.comp.wrapErr("evalsha", err)
+}
+
+// reserve 取出一个任务，没有任务时返回 nil
+func (q *DelayQueue) reserve(ctx context.Context) (*Job, error) {
+	keys := []string{q.keys.ready, q.keys.processing, q.keys.jobs, q.keys.attempts, q.keys.dead}
+	res, err := luaDelayReserve.Run(ctx, q.comp.client, keys, time.Now().Add(q.visibility).UnixMilli(), q.maxRetries).Slice()
+	if IsNil(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, q.comp.wrapErr("evalsha", err)
+	}
+	if len(res) != 3 {
+		return nil, fmt.Errorf("eredis: unexpected reserve reply %v", res)
+	}
+	id, _ := res[0].(string)
+	payload, _ := res[1].(string)
+	attempts, _ := res[2].(int64)
+	return &Job{ID: id, Payload: payload, Attempts: attempts}, nil
+}
+
+// ack 任务完成，删除任务
+func (q *DelayQueue) ack(ctx context.Context, id string) error {
+	_, err := q.comp.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, q.keys.processing, id)
+		pipe.HDel(ctx, q.keys.jobs, id)
+		pipe.HDel(ctx, q.keys.attempts, id)
+		return nil
+	})
+	return q.comp.wrapErr("hdel", err)
+}
+
+// fail 任务失败，未超过最大重试次数时延迟重试，否则移动到死信 hash，返回是否进入死信
+func (q *DelayQueue) fail(ctx context.Context, job *Job) (bool, error) {
+	if q.maxRetries >= 0 && job.Attempts > q.maxRetries {
+		keys := []string{q.keys.processing, q.keys.jobs, q.keys.attempts, q.keys.dead}
+		return true, q.comp.wrapErr("evalsha", luaQueueDead.Run(ctx, q.comp.client, keys, job.ID).Err())
+	}
+	runAt := time.Now().Add(q.backoff(job.Attempts))
+	keys := []string{q.keys.processing, q.keys.delayed}
+	return false, q.comp.wrapErr("evalsha", luaDelayRetry.Run(ctx, q.comp.client, keys, job.ID, runAt.UnixMilli()).Err())
+}
+
+// NewWorker 创建处理任务的 worker 池
+func (q *DelayQueue) NewWorker(handler JobHandler) *DelayQueueWorker {
+	return &DelayQueueWorker{
+		queue:          q,
+		handler:        handler,
+		logger:         q.comp.logger.With(elog.String("queue", q.name)),
+		consumerServer: consumerServer{name: q.comp.name, scheme: "redis-delay-queue", address: q.name},
+		workerGroup:    newWorkerGroup(),
+	}
+}
+
+var _ server.Server = (*DelayQueueWorker)(nil)
+
+// DelayQueueWorker 延迟队列的 worker 池，由 DelayQueue.NewWorker 创建。
+// 实现了 ego 的 server.Server 接口，可以通过 ego.New().Serve(worker) 启动，随应用优雅退出。
+// 任务执行时间超过 visibility timeout 时会被重新投递，handler 需要保证幂等。
+type DelayQueueWorker struct {
+	queue   *DelayQueue
+	handler JobHandler
+	logger  *elog.Component
+
+	consumerServer
+	workerGroup
+}
+
+// Start 启动 worker，阻塞直到停止
+func (w *DelayQueueWorker) Start() error {
+	w.run(w.promote)
+	for i := 0; i < w.queue.workers; i++ {
+		w.run(w.work)
+	}
+	w.wg.Wait()
+	return nil
+}
+
+// Stop 立即停止，正在处理的任务的 context 被取消
+func (w *DelayQueueWorker) Stop() error {
+	w.stop()
+	return nil
+}
+
+// GracefulStop 停止取出新任务，等待正在处理的任务完成，ctx 结束时取消正在处理的任务
+func (w *DelayQueueWorker) GracefulStop(ctx context.Context) error {
+	w.gracefulStop(ctx)
+	return nil
+}
+
+// promote 定期移动到期的任务
+func (w *DelayQueueWorker) promote() {
+	const limit = 100
+	for w.sleep(w.queue.pollInterval) {
+		for !w.stopped() {
+			n, err := w.queue.promote(w.ctx, limit)
+			if err != nil {
+				w.logger.Error("promote delayed jobs fail", elog.FieldName(w.queue.comp.name), elog.FieldErr(err))
+			}
+			if n < limit {
+				break
+			}
+		}
+	}
+}
+
+// work 取出任务并执行
+func (w *DelayQueueWorker) work() {
+	for !w.stopped() {
+		job, err := w.queue.reserve(w.ctx)
+		if err != nil {
+			w.logger.Error("reserve job fail", elog.FieldName(w.queue.comp.name), elog.FieldErr(err))
+		}
+		if job == nil {
+			if !w.sleep(w.queue.pollInterval) {
+				return
+			}
+			continue
+		}
+		w.handle(job)
+	}
+}
+
+func (w *DelayQueueWorker) handle(job *Job) {
+	name := w.queue.comp.name
+	if err := callHandler("job", func() error { return w.handler(w.ctx, job) }); err != nil {
+		dead, e := w.queue.fail(w.ctx, job)
+		result := "error"
+		if dead {
+			result = "dead_letter"
+		}
+		queueJobCounter.Inc(name, w.queue.name, result)
+		w.logger.Error("handle job fail", elog.FieldName(name), elog.String("id", job.ID), elog.Int64("attempts", job.Attempts), elog.Any("dead", dead), elog.FieldErr(err))
+		if e != nil {
+			w.logger.Error("retry job fail", elog.FieldName(name), elog.String("id", job.ID), elog.FieldErr(e))
+		}
+		return
+	}
+	queueJobCounter.Inc(name, w.queue.name, "ok")
+	if err := w.queue.ack(w.ctx, job.ID); err != nil {
+		w.logger.Error("ack job fail", elog.FieldName(name), elog.String("id", job.ID), elog.FieldErr(err))
+	}
+}
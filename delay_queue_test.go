@@ -0,0 +1,122 @@
+package eredis
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoublingBackoff(t *testing.T) {
+	backoff := doublingBackoff(time.Second, 5*time.Second)
+	assert.Equal(t, time.Second, backoff(1))
+	assert.Equal(t, 4*time.Second, backoff(3))
+	assert.Equal(t, 5*time.Second, backoff(10))
+}
+
+// newTestDelayQueue 创建使用唯一名称的延迟队列，测试结束后删除所有 key
+func newTestDelayQueue(t *testing.T, comp *Component, opts ...DelayQueueOption) *DelayQueue {
+	name := newTestKey(t, comp, "delay")
+	q := comp.NewDelayQueue(name, opts...)
+	t.Cleanup(func() {
+		_, _ = comp.Del(context.Background(), q.keys.delayed, q.keys.ready, q.keys.processing, q.keys.jobs, q.keys.attempts, q.keys.dead)
+	})
+	return q
+}
+
+func TestDelayQueueReserve(t *testing.T) {
+	comp := newTestRedis(t, "redis.delayTest")
+	ctx := context.Background()
+	q := newTestDelayQueue(t, comp, WithDelayQueueMaxRetries(1), WithDelayQueueVisibilityTimeout(-time.Second))
+
+	// 未到期的任务不会被取出，已经取消的任务直接丢弃
+	id, err := q.Enqueue(ctx, "mail", time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+	_, err = q.Enqueue(ctx, "later", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	canceled, err := q.Enqueue(ctx, "canceled", time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+	ok, err := q.Cancel(ctx, canceled)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	n, err := q.promote(ctx, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	job, err := q.reserve(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, &Job{ID: id, Payload: "mail", Attempts: 1}, job)
+
+	// worker 崩溃时任务在 visibility timeout 之后重新投递，超过最大重试次数之后由 reserve 移动到死信 hash
+	for attempts := int64(2); attempts <= 3; attempts++ {
+		_, err = q.promote(ctx, 100)
+		assert.NoError(t, err)
+		job, err = q.reserve(ctx)
+		assert.NoError(t, err)
+		if attempts == 2 {
+			assert.Equal(t, int64(2), job.Attempts)
+		} else {
+			assert.Nil(t, job)
+		}
+	}
+	payload, err := comp.HGet(ctx, q.keys.dead, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "mail", payload)
+	exists, err := comp.Client().HExists(ctx, q.keys.jobs, id).Result()
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestDelayQueueWorker(t *testing.T) {
+	comp := newTestRedis(t, "redis.delayTest")
+	ctx := context.Background()
+	q := newTestDelayQueue(t, comp,
+		WithDelayQueuePollInterval(10*time.Millisecond),
+		WithDelayQueueMaxRetries(2),
+		WithDelayQueueBackoff(func(int64) time.Duration { return 10 * time.Millisecond }),
+	)
+
+	var (
+		mu       sync.Mutex
+		attempts = make(map[string]int64)
+	)
+	w := q.NewWorker(func(ctx context.Context, job *Job) error {
+		mu.Lock()
+		attempts[job.Payload] = job.Attempts
+		mu.Unlock()
+		if job.Payload == "bad" {
+			panic("boom")
+		}
+		return nil
+	})
+	go func() { _ = w.Start() }()
+	defer w.Stop()
+
+	good, err := q.Enqueue(ctx, "good", time.Now().Add(50*time.Millisecond))
+	assert.NoError(t, err)
+	bad, err := q.Enqueue(ctx, "bad", time.Now())
+	assert.NoError(t, err)
+
+	// 失败的任务按退避时间重试，超过最大重试次数之后移动到死信 hash
+	assert.Eventually(t, func() bool {
+		payload, err := comp.HGet(ctx, q.keys.dead, bad)
+		return err == nil && payload == "bad"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		n, err := comp.Client().HLen(ctx, q.keys.jobs).Result()
+		return err == nil && n == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, int64(1), attempts["good"])
+	assert.Equal(t, int64(3), attempts["bad"])
+	mu.Unlock()
+
+	// 已经完成的任务无法取消
+	ok, err := q.Cancel(ctx, good)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, w.GracefulStop(ctx))
+}
@@ -0,0 +1,248 @@
+package eredis
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"golang.org/x/sync/singleflight"
+)
+
+// encryptMagic 加密值的头部，之后依次为 1 字节密钥 ID 长度、密钥 ID、nonce、密文
+var encryptMagic = []byte{0xfe, 'e', 'k'}
+
+// minKeyReload 遇到未知密钥 ID 时重新加载密钥的最小间隔
+const minKeyReload = time.Second
+
+// EncryptionConfig 值加密配置，使用 AES-GCM 加密 string、hash 方法写入的值，通过 Client() 调用时不生效。
+// 密文与 key、hash 字段绑定，复制到其他 key 或字段后无法解密
+type EncryptionConfig struct {
+	KeyFile        string        // KeyFile 密钥文件，每行一个 "密钥ID:base64 编码的 16/24/32 字节密钥"，第一行为当前加密使用的密钥，其余为解密旧值使用的历史密钥
+	Prefixes       []string      // Prefixes 只加密这些前缀的 key，为空时加密所有值；这些 key 读到未加密的值时返回错误
+	AllowPlaintext bool          // AllowPlaintext 允许读取需要加密的 key 中未加密的旧值，用于开启加密前已有数据的迁移，默认 false
+	ReloadInterval time.Duration // ReloadInterval 定期在后台重新加载密钥，用于密钥轮转，0 表示不重新加载
+}
+
+// EncryptionKey 加密密钥
+type EncryptionKey struct {
+	ID  string // ID 密钥 ID，写入密文头部，最长 255 字节
+	Key []byte // Key AES 密钥，16、24 或 32 字节
+}
+
+// KeyLoader 加载密钥，第一个为当前加密使用的密钥，其余为解密旧值使用的历史密钥
+type KeyLoader func() ([]EncryptionKey, error)
+
+// FileKeyLoader 从文件加载密钥，空行和 # 开头的行会被忽略
+func FileKeyLoader(path string) KeyLoader {
+	return func() ([]EncryptionKey, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var keys []EncryptionKey
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			id, encoded, ok := strings.Cut(line, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid key line in %s", path)
+			}
+			key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %s in %s: %w", id, path, err)
+			}
+			keys = append(keys, EncryptionKey{ID: strings.TrimSpace(id), Key: key})
+		}
+		return keys, scanner.Err()
+	}
+}
+
+type aeadKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// encryptor 使用当前密钥加密，读取时根据头部的密钥 ID 选择密钥解密
+type encryptor struct {
+	loader         KeyLoader
+	prefixes       []string
+	allowPlaintext bool
+	interval       time.Duration
+	logger         *elog.Component
+	now            func() time.Time
+	group          singleflight.Group
+
+	mu       sync.RWMutex
+	current  *aeadKey
+	keys     map[string]*aeadKey
+	loadedAt time.Time
+}
+
+func newEncryptor(config EncryptionConfig, loader KeyLoader, logger *elog.Component) (*encryptor, error) {
+	if loader == nil {
+		loader = FileKeyLoader(config.KeyFile)
+	}
+	e := &encryptor{
+		loader:         loader,
+		prefixes:       config.Prefixes,
+		allowPlaintext: config.AllowPlaintext,
+		interval:       config.ReloadInterval,
+		logger:         logger,
+		now:            time.Now,
+	}
+	if err := e.reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *encryptor) name() string {
+	return "aes-gcm"
+}
+
+// reload 重新加载密钥
+func (e *encryptor) reload() error {
+	keys, err := e.loader()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no encryption keys")
+	}
+	ring := make(map[string]*aeadKey, len(keys))
+	for _, k := range keys {
+		if k.ID == "" || len(k.ID) > 255 {
+			return fmt.Errorf("invalid encryption key id %q", k.ID)
+		}
+		block, err := aes.NewCipher(k.Key)
+		if err != nil {
+			return fmt.Errorf("invalid encryption key %s: %w", k.ID, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+		ring[k.ID] = &aeadKey{id: k.ID, aead: aead}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.current = ring[keys[0].ID]
+	e.keys = ring
+	e.loadedAt = e.now()
+	return nil
+}
+
+// maybeReload 到达重新加载间隔时在后台重新加载，不阻塞读写；遇到未知密钥 ID 时需要等待重新加载完成。
+// 并发的重新加载通过 singleflight 合并，失败时继续使用原来的密钥
+func (e *encryptor) maybeReload(unknownKey bool) {
+	e.mu.RLock()
+	elapsed := e.now().Sub(e.loadedAt)
+	e.mu.RUnlock()
+	switch {
+	case unknownKey && (elapsed >= minKeyReload || e.interval > 0 && elapsed >= e.interval):
+		_, _, _ = e.group.Do("reload", e.tryReload)
+	case e.interval > 0 && elapsed >= e.interval:
+		e.group.DoChan("reload", e.tryReload)
+	}
+}
+
+func (e *encryptor) tryReload() (interface{}, error) {
+	if err := e.reload(); err != nil {
+		e.logger.Error("reload encryption keys fail", elog.FieldErr(err))
+		e.mu.Lock()
+		e.loadedAt = e.now()
+		e.mu.Unlock()
+	}
+	return nil, nil
+}
+
+func (e *encryptor) encode(key, field string, data []byte) ([]byte, error) {
+	if !e.match(key) {
+		return data, nil
+	}
+	e.maybeReload(false)
+	e.mu.RLock()
+	k := e.current
+	e.mu.RUnlock()
+
+	nonceSize := k.aead.NonceSize()
+	buf := make([]byte, 0, len(encryptMagic)+1+len(k.id)+nonceSize+len(data)+k.aead.Overhead())
+	buf = append(buf, encryptMagic...)
+	buf = append(buf, byte(len(k.id)))
+	buf = append(buf, k.id...)
+	nonce := buf[len(buf) : len(buf)+nonceSize]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	buf = buf[:len(buf)+nonceSize]
+	return k.aead.Seal(buf, nonce, data, additionalData(key, field)), nil
+}
+
+// decode 解密，不需要加密的 key 中未加密的值原样返回
+func (e *encryptor) decode(key, field string, data []byte) ([]byte, error) {
+	if len(data) <= len(encryptMagic) || !bytes.HasPrefix(data, encryptMagic) {
+		if e.match(key) && !e.allowPlaintext {
+			return nil, fmt.Errorf("unencrypted value")
+		}
+		return data, nil
+	}
+	data = data[len(encryptMagic):]
+	idLen := int(data[0])
+	if len(data) < 1+idLen {
+		return nil, fmt.Errorf("malformed encrypted value")
+	}
+	id, data := string(data[1:1+idLen]), data[1+idLen:]
+
+	k := e.key(id)
+	if k == nil {
+		e.maybeReload(true)
+		if k = e.key(id); k == nil {
+			return nil, fmt.Errorf("unknown encryption key %q", id)
+		}
+	}
+	nonceSize := k.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("malformed encrypted value")
+	}
+	return k.aead.Open(nil, data[:nonceSize], data[nonceSize:], additionalData(key, field))
+}
+
+// additionalData 以 key 和 hash 字段作为 AEAD 的附加数据，key 前面加上长度避免与字段的边界产生歧义
+func additionalData(key, field string) []byte {
+	buf := make([]byte, 0, binary.MaxVarintLen64+len(key)+len(field))
+	buf = binary.AppendUvarint(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	return append(buf, field...)
+}
+
+func (e *encryptor) key(id string) *aeadKey {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.keys[id]
+}
+
+func (e *encryptor) match(key string) bool {
+	if len(e.prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range e.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
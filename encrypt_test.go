@@ -0,0 +1,155 @@
+package eredis
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptorRotation(t *testing.T) {
+	k1 := EncryptionKey{ID: "k1", Key: bytes.Repeat([]byte{1}, 32)}
+	k2 := EncryptionKey{ID: "k2", Key: bytes.Repeat([]byte{2}, 16)}
+	keys := []EncryptionKey{k1}
+	e, err := newEncryptor(EncryptionConfig{}, func() ([]EncryptionKey, error) { return keys, nil }, elog.DefaultLogger)
+	assert.NoError(t, err)
+
+	old, err := e.encode("user:1", "", []byte("secret"))
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(old, encryptMagic))
+	assert.NotContains(t, string(old), "secret")
+
+	// 轮转后使用新密钥加密，旧密钥仍然可以解密
+	keys = []EncryptionKey{k2, k1}
+	assert.NoError(t, e.reload())
+	cur, err := e.encode("user:1", "", []byte("secret"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(cur), "k2")
+	for _, data := range [][]byte{old, cur} {
+		plain, err := e.decode("user:1", "", data)
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", string(plain))
+	}
+
+	// 篡改的密文无法解密
+	cur[len(cur)-1] ^= 1
+	_, err = e.decode("user:1", "", cur)
+	assert.Error(t, err)
+
+	// 其他实例已经轮转到新密钥，遇到未知密钥 ID 时重新加载
+	k3 := EncryptionKey{ID: "k3", Key: bytes.Repeat([]byte{3}, 32)}
+	keys = []EncryptionKey{k3, k2}
+	other, err := newEncryptor(EncryptionConfig{}, func() ([]EncryptionKey, error) { return keys, nil }, elog.DefaultLogger)
+	assert.NoError(t, err)
+	data, _ := other.encode("user:1", "", []byte("secret"))
+	now := time.Now().Add(minKeyReload)
+	e.now = func() time.Time { return now }
+	plain, err := e.decode("user:1", "", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(plain))
+}
+
+func TestEncryptorPrefixes(t *testing.T) {
+	loader := func() ([]EncryptionKey, error) {
+		return []EncryptionKey{{ID: "k1", Key: bytes.Repeat([]byte{1}, 32)}}, nil
+	}
+	e, err := newEncryptor(EncryptionConfig{Prefixes: []string{"pii:"}}, loader, elog.DefaultLogger)
+	assert.NoError(t, err)
+	out, _ := e.encode("cache:1", "", []byte("public"))
+	assert.Equal(t, "public", string(out))
+	out, _ = e.encode("pii:1", "", []byte("phone"))
+	assert.True(t, bytes.HasPrefix(out, encryptMagic))
+
+	// 不需要加密的 key 中未加密的值原样返回，需要加密的 key 中未加密的值返回错误
+	plain, err := e.decode("cache:1", "", []byte("public"))
+	assert.NoError(t, err)
+	assert.Equal(t, "public", string(plain))
+	_, err = e.decode("pii:1", "", []byte("phone"))
+	assert.Error(t, err)
+
+	// 迁移期间允许读取未加密的旧值
+	e, err = newEncryptor(EncryptionConfig{Prefixes: []string{"pii:"}, AllowPlaintext: true}, loader, elog.DefaultLogger)
+	assert.NoError(t, err)
+	plain, err = e.decode("pii:1", "", []byte("phone"))
+	assert.NoError(t, err)
+	assert.Equal(t, "phone", string(plain))
+}
+
+func TestEncryptorAdditionalData(t *testing.T) {
+	loader := func() ([]EncryptionKey, error) {
+		return []EncryptionKey{{ID: "k1", Key: bytes.Repeat([]byte{1}, 32)}}, nil
+	}
+	e, err := newEncryptor(EncryptionConfig{}, loader, elog.DefaultLogger)
+	assert.NoError(t, err)
+
+	data, err := e.encode("user:1", "phone", []byte("secret"))
+	assert.NoError(t, err)
+	plain, err := e.decode("user:1", "phone", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(plain))
+
+	// 密文复制到其他 key 或字段后无法解密
+	for _, target := range [][2]string{{"user:2", "phone"}, {"user:1", "email"}, {"user:1p", "hone"}} {
+		_, err = e.decode(target[0], target[1], data)
+		assert.Error(t, err, target)
+	}
+}
+
+func TestEncryptorBackgroundReload(t *testing.T) {
+	k1 := EncryptionKey{ID: "k1", Key: bytes.Repeat([]byte{1}, 32)}
+	k2 := EncryptionKey{ID: "k2", Key: bytes.Repeat([]byte{2}, 32)}
+	var loads int32
+	release := make(chan struct{})
+	loader := func() ([]EncryptionKey, error) {
+		if atomic.AddInt32(&loads, 1) == 1 {
+			return []EncryptionKey{k1}, nil
+		}
+		<-release
+		return []EncryptionKey{k2, k1}, nil
+	}
+	e, err := newEncryptor(EncryptionConfig{ReloadInterval: time.Minute}, loader, elog.DefaultLogger)
+	assert.NoError(t, err)
+	now := time.Now().Add(time.Minute)
+	e.now = func() time.Time { return now }
+
+	// 到达重新加载间隔时不等待加载完成，继续使用原来的密钥，并发的加载只执行一次
+	for i := 0; i < 10; i++ {
+		data, err := e.encode("user:1", "", []byte("secret"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "k1")
+	}
+	close(release)
+	assert.Eventually(t, func() bool {
+		data, _ := e.encode("user:1", "", []byte("secret"))
+		return strings.Contains(string(data), "k2")
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loads))
+}
+
+func TestFileKeyLoader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	content := strings.Join([]string{
+		"# current key first",
+		"k2:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32)),
+		"k1:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 16)),
+	}, "\n")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	keys, err := FileKeyLoader(path)()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "k2", keys[0].ID)
+	assert.Len(t, keys[1].Key, 16)
+
+	_, err = newEncryptor(EncryptionConfig{KeyFile: path}, nil, elog.DefaultLogger)
+	assert.NoError(t, err)
+	_, err = newEncryptor(EncryptionConfig{KeyFile: path + ".missing"}, nil, elog.DefaultLogger)
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+package eratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotomicro/ego/core/elog"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/ego-component/eredis"
+)
+
+// GinKeyFunc 从 HTTP 请求中提取限流 key，返回空字符串表示不限流
+type GinKeyFunc func(c *gin.Context) string
+
+// GRPCKeyFunc 从 gRPC 请求中提取限流 key，返回空字符串表示不限流
+type GRPCKeyFunc func(ctx context.Context, info *grpc.UnaryServerInfo) string
+
+// GinClientIP 按客户端 IP 限流
+func GinClientIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// GRPCFullMethod 按 gRPC 方法限流
+func GRPCFullMethod(ctx context.Context, info *grpc.UnaryServerInfo) string {
+	return info.FullMethod
+}
+
+// GinMiddleware 返回 egin 可用的限流中间件，被限流时返回 429 并设置 Retry-After 头。
+// redis 出错时默认放行，可以通过 WithFailClosed 改为拒绝。
+func GinMiddleware(limiter *eredis.Limiter, limit eredis.Limit, keyFunc GinKeyFunc, opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts)
+	return func(c *gin.Context) {
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+		res, err := limiter.Allow(c.Request.Context(), o.prefix+key, limit)
+		if err != nil {
+			o.logger.Error("rate limit error", elog.FieldErr(err), elog.FieldKey(key))
+			if o.failClosed {
+				c.AbortWithStatus(o.httpStatus)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Rate))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		if res.Allowed == 0 {
+			if res.RetryAfter > 0 {
+				c.Header("Retry-After", strconv.Itoa(int((res.RetryAfter+time.Second-1)/time.Second)))
+			}
+			c.AbortWithStatus(o.httpStatus)
+			return
+		}
+		c.Next()
+	}
+}
+
+// UnaryServerInterceptor 返回 egrpc 可用的限流拦截器，被限流时返回 ResourceExhausted，并在 details 中携带 RetryInfo。
+// redis 出错时默认放行，可以通过 WithFailClosed 改为拒绝。
+func UnaryServerInterceptor(limiter *eredis.Limiter, limit eredis.Limit, keyFunc GRPCKeyFunc, opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		key := keyFunc(ctx, info)
+		if key == "" {
+			return handler(ctx, req)
+		}
+		res, err := limiter.Allow(ctx, o.prefix+key, limit)
+		if err != nil {
+			o.logger.Error("rate limit error", elog.FieldErr(err), elog.FieldKey(key))
+			if o.failClosed {
+				return nil, status.Error(codes.Unavailable, "rate limiter unavailable")
+			}
+			return handler(ctx, req)
+		}
+		if res.Allowed == 0 {
+			s := status.New(codes.ResourceExhausted, "rate limit exceeded")
+			if res.RetryAfter > 0 {
+				if ds, e := s.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(res.RetryAfter)}); e == nil {
+					s = ds
+				}
+			}
+			return nil, s.Err()
+		}
+		return handler(ctx, req)
+	}
+}
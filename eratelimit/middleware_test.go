@@ -0,0 +1,117 @@
+package eratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gin-gonic/gin"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ego-component/eredis"
+)
+
+func newComponent(t *testing.T, name, addr string) *eredis.Component {
+	conf := fmt.Sprintf(`
+[%s]
+	addr = "%s"
+	dialTimeout = "200ms"
+	maxRetries = -1
+	onFail = "error"
+`, name, addr)
+	assert.NoError(t, econf.LoadFromReader(strings.NewReader(conf), toml.Unmarshal))
+	comp := eredis.Load(name).Build()
+	t.Cleanup(func() { _ = comp.Close() })
+	return comp
+}
+
+// newTestRedis 连接 EREDIS_TEST_ADDR（默认 127.0.0.1:6379）指定的 redis，无法连接时跳过测试
+func newTestRedis(t *testing.T) *eredis.Component {
+	addr := os.Getenv("EREDIS_TEST_ADDR")
+	if addr == "" {
+		addr = "127.0.0.1:6379"
+	}
+	comp := newComponent(t, "redis.ratelimit", addr)
+	if _, err := comp.Ping(context.Background()); err != nil {
+		t.Skipf("redis %s unavailable: %v", addr, err)
+	}
+	return comp
+}
+
+func serveGin(handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", handler, func(c *gin.Context) { c.Status(http.StatusOK) })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w
+}
+
+func callGRPC(interceptor grpc.UnaryServerInterceptor) error {
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Svc/Call"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+	return err
+}
+
+func TestMiddlewareLimit(t *testing.T) {
+	limiter := newTestRedis(t).Limiter()
+	limit := eredis.Limit{Algorithm: eredis.LimitFixedWindow, Rate: 1, Period: time.Minute}
+	prefix := fmt.Sprintf("test:%d:", time.Now().UnixNano())
+	defer limiter.Reset(context.Background(), prefix+"192.0.2.1", limit)
+	defer limiter.Reset(context.Background(), prefix+"/test.Svc/Call", limit)
+
+	handler := GinMiddleware(limiter, limit, func(c *gin.Context) string { return "192.0.2.1" }, WithPrefix(prefix))
+	w := serveGin(handler)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	// 被限流时返回 429 和 Retry-After
+	w = serveGin(handler)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	interceptor := UnaryServerInterceptor(limiter, limit, GRPCFullMethod, WithPrefix(prefix))
+	assert.NoError(t, callGRPC(interceptor))
+	err := callGRPC(interceptor)
+	s := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, s.Code())
+	if assert.Len(t, s.Details(), 1) {
+		info, ok := s.Details()[0].(*errdetails.RetryInfo)
+		assert.True(t, ok)
+		assert.Greater(t, info.GetRetryDelay().AsDuration(), time.Duration(0))
+	}
+}
+
+func TestMiddlewareRedisError(t *testing.T) {
+	limiter := newComponent(t, "redis.ratelimitUnreachable", "127.0.0.1:1").Limiter()
+	limit := eredis.PerSecond(1)
+	keyFunc := func(c *gin.Context) string { return "192.0.2.1" }
+
+	// 默认放行
+	assert.Equal(t, http.StatusOK, serveGin(GinMiddleware(limiter, limit, keyFunc)).Code)
+	assert.NoError(t, callGRPC(UnaryServerInterceptor(limiter, limit, GRPCFullMethod)))
+
+	// WithFailClosed 时拒绝
+	w := serveGin(GinMiddleware(limiter, limit, keyFunc, WithFailClosed(), WithHTTPStatus(http.StatusServiceUnavailable)))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	err := callGRPC(UnaryServerInterceptor(limiter, limit, GRPCFullMethod, WithFailClosed()))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestMiddlewareEmptyKey(t *testing.T) {
+	limiter := newComponent(t, "redis.ratelimitEmpty", "127.0.0.1:1").Limiter()
+	handler := GinMiddleware(limiter, eredis.PerSecond(1), func(c *gin.Context) string { return "" }, WithFailClosed())
+	assert.Equal(t, http.StatusOK, serveGin(handler).Code)
+}
@@ -0,0 +1,51 @@
+package eratelimit
+
+import (
+	"net/http"
+
+	"github.com/gotomicro/ego/core/elog"
+
+	"github.com/ego-component/eredis"
+)
+
+// Option 中间件选项
+type Option func(o *options)
+
+type options struct {
+	prefix     string
+	failClosed bool
+	httpStatus int
+	logger     *elog.Component
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		httpStatus: http.StatusTooManyRequests,
+		logger:     elog.EgoLogger.With(elog.FieldComponent(eredis.PackageName)),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithPrefix 设置限流 key 前缀，用于区分不同的限流规则
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithFailClosed redis 出错时拒绝请求，默认放行
+func WithFailClosed() Option {
+	return func(o *options) {
+		o.failClosed = true
+	}
+}
+
+// WithHTTPStatus 设置 HTTP 请求被限流时的状态码，默认 429
+func WithHTTPStatus(code int) Option {
+	return func(o *options) {
+		o.httpStatus = code
+	}
+}
@@ -1,6 +1,13 @@
 package eredis
 
-import "github.com/redis/go-redis/v9"
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+)
 
 type Err string
 
@@ -16,6 +23,100 @@ const (
 	// ErrLockNotHeld is returned when trying to release an inactive Lock.
 	ErrLockNotHeld = Err("redislock: lock not held")
 
+	// ErrCircuitOpen is returned when the circuit breaker is open and the command is rejected without being sent.
+	ErrCircuitOpen = Err("eredis: circuit breaker is open")
+
+	// ErrThrottled is returned when the command is rejected by the client side rate limiter.
+	ErrThrottled = Err("eredis: command throttled")
+
+	// ErrCommandDenied is returned when the command is rejected by the command guard.
+	ErrCommandDenied = Err("eredis: command denied by guard")
+
+	// ErrPolicyViolation is returned when the write violates the value size or ttl policy.
+	ErrPolicyViolation = Err("eredis: write violates policy")
+
 	// Nil reply returned by Redis when key does not exist.
 	Nil = redis.Nil
 )
+
+// Error 是 Component 方法返回的错误。
+//
+// 错误处理约定：Component 的所有方法在出错时都返回 *Error，其中记录了命令名和节点地址，
+// 原始错误（包括 redis.Nil）可以通过 errors.Is、errors.As 或 IsNil、IsTimeout 等方法判断。
+// 判断 redis 返回的错误前缀（例如 BUSYGROUP）使用 redis.HasErrorPrefix，不能直接比较 err == redis.Nil 或者 err.Error() 的前缀。
+// 通过 Client() 直接调用 go-redis 时返回的仍然是 go-redis 的原始错误。
+type Error struct {
+	Cmd  string // Cmd 命令名，例如 get、mget，组合命令为方法名
+	Node string // Node 节点地址，cluster、sentinel 模式下为配置的地址列表
+	Err  error  // Err 原始错误
+}
+
+func (e *Error) Error() string {
+	return "eredis: command " + e.Cmd + " on " + e.Node + ": " + e.Err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// wrapErr 将错误包装为 *Error，已经是 *Error 的错误保持不变
+func (r *Component) wrapErr(cmd string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Cmd: cmd, Node: r.config.AddrString(), Err: err}
+}
+
+// cmdErr 将命令的错误包装为 *Error
+func (r *Component) cmdErr(cmd redis.Cmder) error {
+	return r.wrapErr(cmd.Name(), cmd.Err())
+}
+
+// IsNil key 不存在时 redis 返回的空结果
+func IsNil(err error) bool {
+	return errors.Is(err, redis.Nil)
+}
+
+// IsTimeout 命令执行超时，包括 context 超时和网络读写超时
+func IsTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var te interface{ Timeout() bool }
+	return errors.As(err, &te) && te.Timeout()
+}
+
+// IsMoved cluster 模式下 slot 已经迁移，redis 返回了 MOVED 或 ASK 重定向
+func IsMoved(err error) bool {
+	return redis.HasErrorPrefix(err, "MOVED ") || redis.HasErrorPrefix(err, "ASK ")
+}
+
+// IsReadOnly 写命令发送到了只读的从节点，通常发生在主从切换期间
+func IsReadOnly(err error) bool {
+	return redis.HasErrorPrefix(err, "READONLY ")
+}
+
+// IsPoolExhausted 连接池已满，或者等待空闲连接超时
+func IsPoolExhausted(err error) bool {
+	return errors.Is(err, redis.ErrPoolExhausted) || errors.Is(err, redis.ErrPoolTimeout)
+}
+
+// IsConnectionError 连接类错误，包括连接被关闭、拒绝、重置以及客户端已关闭
+func IsConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, redis.ErrClosed) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
@@ -0,0 +1,109 @@
+package eredis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 错误分类对应的 reason，写入 gRPC status 的 ErrorInfo 中，ego 的 eerrors.FromError 可以据此还原错误
+const (
+	ReasonNil             = "EREDIS_NIL"
+	ReasonTimeout         = "EREDIS_TIMEOUT"
+	ReasonCanceled        = "EREDIS_CANCELED"
+	ReasonPoolExhausted   = "EREDIS_POOL_EXHAUSTED"
+	ReasonUnavailable     = "EREDIS_UNAVAILABLE"
+	ReasonCircuitOpen     = "EREDIS_CIRCUIT_OPEN"
+	ReasonThrottled       = "EREDIS_THROTTLED"
+	ReasonCommandDenied   = "EREDIS_COMMAND_DENIED"
+	ReasonPolicyViolation = "EREDIS_POLICY_VIOLATION"
+	ReasonInvalidParams   = "EREDIS_INVALID_PARAMS"
+	ReasonLockNotObtained = "EREDIS_LOCK_NOT_OBTAINED"
+	ReasonLockNotHeld     = "EREDIS_LOCK_NOT_HELD"
+	ReasonDecode          = "EREDIS_DECODE"
+	ReasonUnknown         = "EREDIS_UNKNOWN"
+)
+
+// GRPCCode 返回错误对应的 gRPC code 和 reason
+//
+//	redis.Nil                     -> NotFound
+//	context.Canceled              -> Canceled
+//	连接池已满、等待连接超时       -> ResourceExhausted
+//	context 超时、网络读写超时     -> DeadlineExceeded
+//	ErrNotObtained                -> Aborted
+//	ErrLockNotHeld                -> FailedPrecondition
+//	ErrCircuitOpen                -> Unavailable
+//	ErrThrottled                  -> ResourceExhausted
+//	ErrCommandDenied              -> PermissionDenied
+//	ErrPolicyViolation            -> InvalidArgument
+//	ErrInvalidParams              -> InvalidArgument
+//	*DecodeError                  -> Internal
+//	连接错误、READONLY、MOVED 等   -> Unavailable
+//	其他错误                      -> Unknown
+func GRPCCode(err error) (codes.Code, string) {
+	switch {
+	case err == nil:
+		return codes.OK, ""
+	case IsNil(err):
+		return codes.NotFound, ReasonNil
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled, ReasonCanceled
+	case IsPoolExhausted(err):
+		return codes.ResourceExhausted, ReasonPoolExhausted
+	case IsTimeout(err):
+		return codes.DeadlineExceeded, ReasonTimeout
+	case errors.Is(err, ErrNotObtained):
+		return codes.Aborted, ReasonLockNotObtained
+	case errors.Is(err, ErrLockNotHeld):
+		return codes.FailedPrecondition, ReasonLockNotHeld
+	case errors.Is(err, ErrThrottled):
+		return codes.ResourceExhausted, ReasonThrottled
+	case errors.Is(err, ErrCommandDenied):
+		return codes.PermissionDenied, ReasonCommandDenied
+	case errors.Is(err, ErrPolicyViolation):
+		return codes.InvalidArgument, ReasonPolicyViolation
+	case errors.Is(err, ErrCircuitOpen):
+		return codes.Unavailable, ReasonCircuitOpen
+	case errors.Is(err, ErrInvalidParams):
+		return codes.InvalidArgument, ReasonInvalidParams
+	case errors.As(err, new(*DecodeError)):
+		return codes.Internal, ReasonDecode
+	case IsConnectionError(err), IsReadOnly(err), IsMoved(err), isClusterUnavailable(err):
+		return codes.Unavailable, ReasonUnavailable
+	default:
+		return codes.Unknown, ReasonUnknown
+	}
+}
+
+// isClusterUnavailable 节点正在加载数据或者集群暂时不可用
+func isClusterUnavailable(err error) bool {
+	for _, prefix := range []string{"LOADING ", "CLUSTERDOWN ", "MASTERDOWN ", "TRYAGAIN "} {
+		if redis.HasErrorPrefix(err, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// GRPCStatus 实现 gRPC 的 status 接口，gRPC 服务直接返回 *Error 时客户端可以拿到对应的 code
+func (e *Error) GRPCStatus() *status.Status {
+	return grpcStatus(e, map[string]string{"cmd": e.Cmd, "node": e.Node})
+}
+
+// GRPCStatus 实现 gRPC 的 status 接口
+func (e Err) GRPCStatus() *status.Status {
+	return grpcStatus(e, nil)
+}
+
+func grpcStatus(err error, md map[string]string) *status.Status {
+	code, reason := GRPCCode(err)
+	s := status.New(code, err.Error())
+	if ds, e := s.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: PackageName, Metadata: md}); e == nil {
+		return ds
+	}
+	return s
+}
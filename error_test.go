@@ -0,0 +1,114 @@
+package eredis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type redisErr string
+
+func (e redisErr) Error() string { return string(e) }
+func (redisErr) RedisError()     {}
+
+func TestWrapErr(t *testing.T) {
+	cmp := &Component{config: &config{Addr: "127.0.0.1:6379"}}
+	assert.NoError(t, cmp.wrapErr("get", nil))
+
+	err := cmp.wrapErr("get", redis.Nil)
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "get", e.Cmd)
+	assert.Equal(t, "127.0.0.1:6379", e.Node)
+	assert.Equal(t, "eredis: command get on 127.0.0.1:6379: redis: nil", err.Error())
+	assert.True(t, IsNil(err))
+	assert.ErrorIs(t, err, Nil)
+
+	// 已经包装过的错误保持不变
+	assert.Equal(t, err, cmp.wrapErr("mget", err))
+}
+
+func TestErrorClassification(t *testing.T) {
+	wrap := func(err error) error { return &Error{Cmd: "get", Node: "127.0.0.1:6379", Err: err} }
+
+	assert.True(t, IsTimeout(wrap(context.DeadlineExceeded)))
+	assert.True(t, IsTimeout(wrap(&net.OpError{Op: "read", Err: timeoutErr{}})))
+	assert.False(t, IsTimeout(wrap(redis.Nil)))
+
+	assert.True(t, IsMoved(wrap(redisErr("MOVED 3999 127.0.0.1:6381"))))
+	assert.True(t, IsMoved(wrap(redisErr("ASK 3999 127.0.0.1:6381"))))
+	assert.False(t, IsMoved(wrap(errors.New("MOVED 3999 127.0.0.1:6381"))))
+
+	assert.True(t, IsReadOnly(wrap(redisErr("READONLY You can't write against a read only replica."))))
+	assert.False(t, IsReadOnly(wrap(redis.Nil)))
+
+	assert.True(t, IsPoolExhausted(wrap(redis.ErrPoolTimeout)))
+	assert.True(t, IsPoolExhausted(wrap(redis.ErrPoolExhausted)))
+
+	assert.True(t, IsConnectionError(wrap(io.EOF)))
+	assert.True(t, IsConnectionError(wrap(redis.ErrClosed)))
+	assert.True(t, IsConnectionError(wrap(&net.OpError{Op: "dial", Err: errors.New("connection refused")})))
+	assert.False(t, IsConnectionError(wrap(redisErr("ERR unknown command"))))
+	assert.False(t, IsConnectionError(nil))
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestGRPCStatus(t *testing.T) {
+	wrap := func(err error) error { return &Error{Cmd: "get", Node: "127.0.0.1:6379", Err: err} }
+	cases := []struct {
+		err  error
+		code codes.Code
+	}{
+		{wrap(redis.Nil), codes.NotFound},
+		{wrap(context.DeadlineExceeded), codes.DeadlineExceeded},
+		{wrap(context.Canceled), codes.Canceled},
+		{wrap(redis.ErrPoolTimeout), codes.ResourceExhausted},
+		{wrap(io.EOF), codes.Unavailable},
+		{wrap(redisErr("CLUSTERDOWN The cluster is down")), codes.Unavailable},
+		{wrap(redisErr("WRONGTYPE Operation against a key holding the wrong kind of value")), codes.Unknown},
+		{wrap(ErrInvalidParams), codes.InvalidArgument},
+		{ErrNotObtained, codes.Aborted},
+		{ErrLockNotHeld, codes.FailedPrecondition},
+	}
+	for _, c := range cases {
+		s, ok := status.FromError(c.err)
+		assert.True(t, ok, c.err.Error())
+		assert.Equal(t, c.code, s.Code(), c.err.Error())
+	}
+
+	s, _ := status.FromError(wrap(redis.Nil))
+	assert.Len(t, s.Details(), 1)
+	info := s.Details()[0].(*errdetails.ErrorInfo)
+	assert.Equal(t, ReasonNil, info.Reason)
+	assert.Equal(t, "get", info.Metadata["cmd"])
+}
+
+func TestInterceptorErrors(t *testing.T) {
+	comp := newTestRedis(t, "redis.errorTest")
+	ctx := context.Background()
+
+	// 拦截器把命令的错误包装为 *Error，需要通过 IsNil、redis.HasErrorPrefix 等方法取出原始错误判断
+	_, err := comp.Get(ctx, newTestKey(t, comp, "missing"))
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.True(t, IsNil(err))
+	assert.False(t, err == redis.Nil)
+
+	err = comp.Stub().Do(ctx, "NOSUCHCOMMAND").Err()
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "nosuchcommand", e.Cmd)
+	assert.True(t, redis.HasErrorPrefix(err, "unknown command"))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/gotomicro/ego"
+	"github.com/gotomicro/ego/core/eflag"
+	"github.com/gotomicro/ego/core/elog"
+
+	"github.com/ego-component/eredis"
+)
+
+// 分析 keyspace，报告最大的 key、按前缀统计的内存、按类型统计的 key 数量和没有过期时间的 key 占比
+// go run main.go --config=config.toml --pattern="user:*" --limit=100000 > report.json
+func main() {
+	eflag.Register(
+		&eflag.StringFlag{Name: "pattern", Usage: "--pattern", Default: ""},
+		&eflag.IntFlag{Name: "limit", Usage: "--limit, max keys to analyze", Default: 0},
+		&eflag.IntFlag{Name: "top", Usage: "--top, number of biggest keys", Default: 50},
+		&eflag.StringFlag{Name: "interval", Usage: "--interval, min interval between scans", Default: "10ms"},
+	)
+	err := ego.New().Invoker(analyze).Run()
+	if err != nil {
+		elog.Panic("startup", elog.FieldErr(err))
+	}
+}
+
+func analyze() error {
+	interval, err := time.ParseDuration(eflag.String("interval"))
+	if err != nil {
+		return err
+	}
+	report, err := eredis.Load("redis.test").Build().AnalyzeKeyspace(context.Background(), &eredis.AnalyzeOptions{
+		Pattern:  eflag.String("pattern"),
+		Limit:    eflag.Int("limit"),
+		TopN:     int(eflag.Int("top")),
+		Interval: interval,
+	})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
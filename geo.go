@@ -0,0 +1,150 @@
+package eredis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// GeoAddLocations 批量写入地理位置
+func (r *Component) GeoAddLocations(ctx context.Context, key string, locations ...*redis.GeoLocation) (int64, error) {
+	cmd := r.client.GeoAdd(ctx, key, locations...)
+	return cmd.Val(), r.cmdErr(cmd)
+}
+
+// GeoDist 返回两个成员之间的距离，unit 可以是 m、km、ft、mi，成员不存在时返回 Nil
+func (r *Component) GeoDist(ctx context.Context, key string, member1, member2, unit string) (float64, error) {
+	cmd := r.client.GeoDist(ctx, key, member1, member2, unit)
+	return cmd.Val(), r.cmdErr(cmd)
+}
+
+// GeoPos 返回成员的经纬度，成员不存在时对应位置为 nil
+func (r *Component) GeoPos(ctx context.Context, key string, members ...string) ([]*redis.GeoPos, error) {
+	cmd := r.client.GeoPos(ctx, key, members...)
+	return cmd.Val(), r.cmdErr(cmd)
+}
+
+// GeoHash 返回成员的 geohash 字符串
+func (r *Component) GeoHash(ctx context.Context, key string, members ...string) ([]string, error) {
+	cmd := r.client.GeoHash(ctx, key, members...)
+	return cmd.Val(), r.cmdErr(cmd)
+}
+
+// GeoSearch 查询圆形或者矩形范围内的成员，中心可以是成员或者经纬度。
+// Redis 6.2 以下版本不支持 GEOSEARCH，圆形查询会使用 GEORADIUS 代替
+func (r *Component) GeoSearch(ctx context.Context, key string, q *redis.GeoSearchQuery) ([]string, error) {
+	cmd := r.client.GeoSearch(ctx, key, q)
+	if !isUnknownCommand(cmd.Err()) || !geoRadiusCompatible(q) {
+		return cmd.Val(), r.cmdErr(cmd)
+	}
+	locations, err := r.geoRadius(ctx, key, q, &redis.GeoRadiusQuery{})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(locations))
+	for _, location := range locations {
+		names = append(names, location.Name)
+	}
+	return names, nil
+}
+
+// GeoSearchLocation 与 GeoSearch 相同，可以同时返回经纬度、距离和 geohash
+func (r *Component) GeoSearchLocation(ctx context.Context, key string, q *redis.GeoSearchLocationQuery) ([]redis.GeoLocation, error) {
+	cmd := r.client.GeoSearchLocation(ctx, key, q)
+	if !isUnknownCommand(cmd.Err()) || !geoRadiusCompatible(&q.GeoSearchQuery) {
+		return cmd.Val(), r.cmdErr(cmd)
+	}
+	return r.geoRadius(ctx, key, &q.GeoSearchQuery, &redis.GeoRadiusQuery{
+		WithCoord:   q.WithCoord,
+		WithDist:    q.WithDist,
+		WithGeoHash: q.WithHash,
+	})
+}
+
+// GeoSearchStore 把 GeoSearch 的结果保存到 store，StoreDist 为 true 时 score 为距离，返回保存的数量。
+// cluster 模式下 key 与 store 需要位于同一个 slot
+func (r *Component) GeoSearchStore(ctx context.Context, key, store string, q *redis.GeoSearchStoreQuery) (int64, error) {
+	cmd := r.client.GeoSearchStore(ctx, key, store, q)
+	if !isUnknownCommand(cmd.Err()) || !geoRadiusCompatible(&q.GeoSearchQuery) {
+		return cmd.Val(), r.cmdErr(cmd)
+	}
+	query := geoRadiusQuery(&q.GeoSearchQuery, &redis.GeoRadiusQuery{})
+	if q.StoreDist {
+		query.StoreDist = store
+	} else {
+		query.Store = store
+	}
+	var fallback *redis.IntCmd
+	if q.Member != "" {
+		fallback = r.client.GeoRadiusByMemberStore(ctx, key, q.Member, query)
+	} else {
+		fallback = r.client.GeoRadiusStore(ctx, key, q.Longitude, q.Latitude, query)
+	}
+	return fallback.Val(), r.cmdErr(fallback)
+}
+
+// GeoSearchPage 分页查询 GeoSearch 的结果，结果按照距离从近到远排列，返回当前页和总数。
+// offset 为 0 或者 store 已经过期时执行查询并把结果保存到 store，ttl 内的后续分页直接读取 store，
+// 翻页期间位置的变化不会影响结果。cluster 模式下 key 与 store 需要位于同一个 slot
+func (r *Component) GeoSearchPage(ctx context.Context, key, store string, q *redis.GeoSearchQuery, ttl time.Duration, offset, limit int64) ([]redis.GeoLocation, int64, error) {
+	total, err := r.client.ZCard(ctx, store).Result()
+	if err != nil {
+		return nil, 0, r.wrapErr("zcard", err)
+	}
+	if offset == 0 || total == 0 {
+		total, err = r.GeoSearchStore(ctx, key, store, &redis.GeoSearchStoreQuery{GeoSearchQuery: *q, StoreDist: true})
+		if err != nil {
+			return nil, 0, err
+		}
+		if total > 0 && ttl > 0 {
+			if err := r.client.PExpire(ctx, store, ttl).Err(); err != nil {
+				return nil, 0, r.wrapErr("pexpire", err)
+			}
+		}
+	}
+	if limit <= 0 || offset >= total {
+		return nil, total, nil
+	}
+	zs, err := r.client.ZRangeWithScores(ctx, store, offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, 0, r.wrapErr("zrange", err)
+	}
+	locations := make([]redis.GeoLocation, 0, len(zs))
+	for _, z := range zs {
+		name, _ := z.Member.(string)
+		locations = append(locations, redis.GeoLocation{Name: name, Dist: z.Score})
+	}
+	return locations, total, nil
+}
+
+// geoRadius 在不支持 GEOSEARCH 的版本上使用 GEORADIUS、GEORADIUSBYMEMBER 查询
+func (r *Component) geoRadius(ctx context.Context, key string, q *redis.GeoSearchQuery, base *redis.GeoRadiusQuery) ([]redis.GeoLocation, error) {
+	query := geoRadiusQuery(q, base)
+	var cmd *redis.GeoLocationCmd
+	if q.Member != "" {
+		cmd = r.client.GeoRadiusByMember(ctx, key, q.Member, query)
+	} else {
+		cmd = r.client.GeoRadius(ctx, key, q.Longitude, q.Latitude, query)
+	}
+	return cmd.Val(), r.cmdErr(cmd)
+}
+
+// geoRadiusQuery 把圆形查询转换为 GEORADIUS 的参数
+func geoRadiusQuery(q *redis.GeoSearchQuery, base *redis.GeoRadiusQuery) *redis.GeoRadiusQuery {
+	base.Radius = q.Radius
+	base.Unit = q.RadiusUnit
+	base.Sort = q.Sort
+	base.Count = q.Count
+	return base
+}
+
+// geoRadiusCompatible 圆形查询可以使用 GEORADIUS 代替，矩形查询不支持
+func geoRadiusCompatible(q *redis.GeoSearchQuery) bool {
+	return q.BoxWidth == 0 && q.BoxHeight == 0 && q.Radius > 0
+}
+
+// isUnknownCommand 服务端是否不支持该命令，err 可以是拦截器包装之后的 *Error
+func isUnknownCommand(err error) bool {
+	return redis.HasErrorPrefix(err, "unknown command")
+}
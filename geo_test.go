@@ -0,0 +1,89 @@
+package eredis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeoRadiusFallback(t *testing.T) {
+	unknown := redisErr("ERR unknown command 'GEOSEARCH', with args beginning with: ")
+	assert.True(t, isUnknownCommand(unknown))
+	assert.True(t, isUnknownCommand(&Error{Cmd: "geosearch", Err: unknown}))
+	assert.False(t, isUnknownCommand(redisErr("ERR syntax error")))
+	assert.False(t, isUnknownCommand(errors.New("ERR unknown command")))
+	assert.False(t, isUnknownCommand(nil))
+
+	q := &redis.GeoSearchQuery{Member: "home", Radius: 5, RadiusUnit: "km", Sort: "ASC", Count: 10}
+	assert.True(t, geoRadiusCompatible(q))
+	query := geoRadiusQuery(q, &redis.GeoRadiusQuery{WithDist: true})
+	assert.Equal(t, &redis.GeoRadiusQuery{Radius: 5, Unit: "km", Sort: "ASC", Count: 10, WithDist: true}, query)
+
+	// 矩形查询没有对应的旧命令
+	assert.False(t, geoRadiusCompatible(&redis.GeoSearchQuery{BoxWidth: 1, BoxHeight: 1, BoxUnit: "km"}))
+}
+
+// unknownCommandHook 模拟不支持 GEOSEARCH 的旧版本 Redis
+type unknownCommandHook struct{}
+
+func (unknownCommandHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (unknownCommandHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "geosearch" || cmd.Name() == "geosearchstore" {
+			err := redisErr("ERR unknown command '" + cmd.Name() + "', with args beginning with: ")
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (unknownCommandHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestGeoSearchFallback(t *testing.T) {
+	comp := newTestRedis(t, "redis.geoTest")
+	comp.Stub().AddHook(unknownCommandHook{})
+	ctx := context.Background()
+	key := newTestKey(t, comp, "geo")
+	store := newTestKey(t, comp, "geostore")
+
+	_, err := comp.GeoAddLocations(ctx, key,
+		&redis.GeoLocation{Name: "a", Longitude: 116.40, Latitude: 39.90},
+		&redis.GeoLocation{Name: "b", Longitude: 116.41, Latitude: 39.91},
+		&redis.GeoLocation{Name: "far", Longitude: 121.47, Latitude: 31.23},
+	)
+	assert.NoError(t, err)
+
+	// 圆形查询使用 GEORADIUS、GEORADIUSBYMEMBER 代替
+	q := redis.GeoSearchQuery{Longitude: 116.40, Latitude: 39.90, Radius: 5, RadiusUnit: "km", Sort: "ASC"}
+	names, err := comp.GeoSearch(ctx, key, &q)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, names)
+
+	names, err = comp.GeoSearch(ctx, key, &redis.GeoSearchQuery{Member: "b", Radius: 5, RadiusUnit: "km", Sort: "ASC"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "a"}, names)
+
+	locations, err := comp.GeoSearchLocation(ctx, key, &redis.GeoSearchLocationQuery{GeoSearchQuery: q, WithDist: true})
+	assert.NoError(t, err)
+	if assert.Len(t, locations, 2) {
+		assert.Equal(t, "a", locations[0].Name)
+		assert.Less(t, locations[0].Dist, locations[1].Dist)
+	}
+
+	n, err := comp.GeoSearchStore(ctx, key, store, &redis.GeoSearchStoreQuery{GeoSearchQuery: q})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	// 矩形查询返回原来的错误
+	_, err = comp.GeoSearch(ctx, key, &redis.GeoSearchQuery{Longitude: 116.40, Latitude: 39.90, BoxWidth: 10, BoxHeight: 10, BoxUnit: "km"})
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.True(t, isUnknownCommand(err))
+}
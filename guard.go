@@ -0,0 +1,140 @@
+package eredis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gotomicro/ego/core/eapp"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/redis/go-redis/v9"
+)
+
+// 命令守卫模式
+const (
+	// GuardModeReject 拒绝被禁止的命令，返回 ErrCommandDenied
+	GuardModeReject = "reject"
+	// GuardModeWarn 只记录日志和监控，命令正常执行，用于灰度上线
+	GuardModeWarn = "warn"
+)
+
+var guardViolationCounter = emetric.CounterVecOpts{
+	Namespace: emetric.DefaultNamespace,
+	Name:      "client_redis_guard_violation_total",
+	Help:      "redis commands denied by command guard",
+	Labels:    []string{"name", "cmd", "mode"},
+}.Build()
+
+// GuardConfig 命令守卫配置，在命令发送之前拦截 KEYS、FLUSHALL 等危险命令
+type GuardConfig struct {
+	Mode            string   // Mode 守卫模式 reject|warn，默认 reject
+	Deny            []string // Deny 禁止的命令，子命令使用空格分隔，例如 "config set"
+	Allow           []string // Allow 允许的命令，优先级高于 Deny，例如 "debug sleep"
+	DevelopmentDeny []string // DevelopmentDeny 开发模式（eapp.IsDevelopmentMode）下禁止的命令，默认为空，即开发模式下不做限制
+}
+
+// DefaultGuardConfig 默认命令守卫配置
+func DefaultGuardConfig() GuardConfig {
+	return GuardConfig{
+		Mode: GuardModeReject,
+		Deny: []string{
+			"keys", "flushdb", "flushall", "debug", "shutdown", "monitor",
+			"config set", "config resetstat", "config rewrite", "script flush", "function flush", "cluster reset",
+		},
+	}
+}
+
+// guard 命令黑白名单
+type guard struct {
+	deny  map[string]struct{}
+	allow map[string]struct{}
+}
+
+func newGuard(config GuardConfig, development bool) *guard {
+	deny := config.Deny
+	if development {
+		deny = config.DevelopmentDeny
+	}
+	return &guard{deny: commandSet(deny), allow: commandSet(config.Allow)}
+}
+
+func commandSet(commands []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(commands))
+	for _, c := range commands {
+		set[strings.Join(strings.Fields(strings.ToLower(c)), " ")] = struct{}{}
+	}
+	return set
+}
+
+// denied 返回命令命中的规则，未命中时返回空
+func (g *guard) denied(cmd redis.Cmder) string {
+	name := strings.ToLower(cmd.Name())
+	full := name
+	if args := cmd.Args(); len(args) > 1 {
+		full = name + " " + strings.ToLower(fmt.Sprint(args[1]))
+	}
+	if _, ok := g.allow[full]; ok {
+		return ""
+	}
+	if _, ok := g.allow[name]; ok {
+		return ""
+	}
+	if _, ok := g.deny[full]; ok {
+		return full
+	}
+	if _, ok := g.deny[name]; ok {
+		return name
+	}
+	return ""
+}
+
+// guardInterceptor 命令守卫拦截器，拒绝模式下被禁止的命令不会发送到 redis
+func guardInterceptor(compName string, config *config, logger *elog.Component) *interceptor {
+	return newGuardInterceptor(compName, config, logger, newGuard(config.Guard, eapp.IsDevelopmentMode()))
+}
+
+func newGuardInterceptor(compName string, config *config, logger *elog.Component, g *guard) *interceptor {
+	mode := GuardModeReject
+	if config.Guard.Mode == GuardModeWarn {
+		mode = GuardModeWarn
+	}
+
+	check := func(cmds ...redis.Cmder) error {
+		for _, cmd := range cmds {
+			rule := g.denied(cmd)
+			if rule == "" {
+				continue
+			}
+			guardViolationCounter.Inc(compName, rule, mode)
+			logger.Warn("command denied by guard",
+				elog.FieldName(compName),
+				elog.FieldMethod(rule),
+				elog.String("caller", fileWithLineNum()),
+				elog.String("mode", mode),
+			)
+			if mode == GuardModeReject {
+				return ErrCommandDenied
+			}
+		}
+		return nil
+	}
+
+	return newInterceptor(compName, config, logger).
+		setBeforeProcess(func(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+			if err := check(cmd); err != nil {
+				cmd.SetErr(err)
+				return ctx, err
+			}
+			return ctx, nil
+		}).
+		setBeforeProcessPipeline(func(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+			if err := check(cmds...); err != nil {
+				for _, cmd := range cmds {
+					cmd.SetErr(err)
+				}
+				return ctx, err
+			}
+			return ctx, nil
+		})
+}
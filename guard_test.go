@@ -0,0 +1,83 @@
+package eredis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuardDenied(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultGuardConfig()
+	config.Allow = []string{"DEBUG sleep"}
+	g := newGuard(config, false)
+
+	cases := []struct {
+		args []interface{}
+		want string
+	}{
+		{[]interface{}{"get", "a"}, ""},
+		{[]interface{}{"keys", "*"}, "keys"},
+		{[]interface{}{"FLUSHALL"}, "flushall"},
+		{[]interface{}{"config", "SET", "maxmemory", "0"}, "config set"},
+		{[]interface{}{"config", "get", "maxmemory"}, ""},
+		{[]interface{}{"debug", "sleep", 0}, ""},
+		{[]interface{}{"debug", "object", "a"}, "debug"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, g.denied(redis.NewCmd(ctx, c.args...)), c.args)
+	}
+
+	// 开发模式下使用 DevelopmentDeny
+	config.DevelopmentDeny = []string{"flushall"}
+	g = newGuard(config, true)
+	assert.Equal(t, "", g.denied(redis.NewCmd(ctx, "keys", "*")))
+	assert.Equal(t, "flushall", g.denied(redis.NewCmd(ctx, "flushall")))
+}
+
+func TestGuardInterceptor(t *testing.T) {
+	ctx := context.Background()
+	build := func(name string, mode string, development bool) (*Component, *commandRecorder) {
+		cfg := DefaultConfig()
+		cfg.Guard.Mode = mode
+		cfg.Guard.DevelopmentDeny = []string{"flushall"}
+		recorder := &commandRecorder{}
+		g := newGuardInterceptor(name, cfg, elog.DefaultLogger, newGuard(cfg.Guard, development))
+		return newTestRedis(t, name, withInterceptor(g), withInterceptor(recorder)), recorder
+	}
+
+	// reject 模式下被禁止的命令不会发送到 redis
+	comp, recorder := build("redis.guardRejectTest", GuardModeReject, false)
+	err := comp.Stub().Keys(ctx, "eredis:test:guard:*").Err()
+	assert.ErrorIs(t, err, ErrCommandDenied)
+	assert.Equal(t, 0, recorder.count("keys"))
+	key := newTestKey(t, comp, "guard")
+	assert.NoError(t, comp.Set(ctx, key, "1", time.Minute))
+
+	// pipeline 中包含被禁止的命令时整个 pipeline 被拒绝
+	var get *redis.StringCmd
+	_, err = comp.Stub().Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pipe.Keys(ctx, "eredis:test:guard:*")
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrCommandDenied)
+	assert.ErrorIs(t, get.Err(), ErrCommandDenied)
+	assert.Equal(t, 0, recorder.count("get"))
+
+	// warn 模式下命令正常执行
+	comp, recorder = build("redis.guardWarnTest", GuardModeWarn, false)
+	keys, err := comp.Stub().Keys(ctx, key).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{key}, keys)
+	assert.Equal(t, 1, recorder.count("keys"))
+
+	// 开发模式下使用 DevelopmentDeny 代替 Deny
+	comp, _ = build("redis.guardDevelopmentTest", GuardModeReject, true)
+	assert.NoError(t, comp.Stub().Keys(ctx, key).Err())
+	assert.ErrorIs(t, comp.Stub().FlushAll(ctx).Err(), ErrCommandDenied)
+}
@@ -0,0 +1,219 @@
+package eredis
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/gotomicro/ego/server/egovernor"
+	"github.com/redis/go-redis/v9"
+)
+
+// count-min sketch 的大小，误差约为 e/width * 采样总数
+const (
+	hotKeySketchWidth = 2048
+	hotKeySketchDepth = 4
+)
+
+var hotKeyGauge = emetric.GaugeVecOpts{
+	Namespace: emetric.DefaultNamespace,
+	Name:      "client_redis_hot_key_qps",
+	Help:      "estimated qps of the top n hot keys in the last window",
+	Labels:    []string{"name", "key"},
+}.Build()
+
+// hotKeyDetectors 组件名称到热 key 统计的映射，用于 egovernor 接口
+var hotKeyDetectors = sync.Map{}
+
+func init() {
+	egovernor.HandleFunc("/debug/redis/hotkeys", func(w http.ResponseWriter, r *http.Request) {
+		ret := make(map[string][]HotKey)
+		hotKeyDetectors.Range(func(key, val interface{}) bool {
+			ret[key.(string)] = val.(*hotKeyDetector).top()
+			return true
+		})
+		if err := json.NewEncoder(w).Encode(ret); err != nil {
+			elog.Error("encode hot keys fail", elog.FieldErr(err))
+		}
+	})
+}
+
+// HotKeyConfig 热 key 统计配置
+type HotKeyConfig struct {
+	Window     time.Duration // Window 统计窗口，默认 10s
+	SampleRate float64       // SampleRate 命令采样率，取值 (0, 1]，默认 0.1
+	TopN       int           // TopN 每个窗口导出的热 key 数量，默认 20
+}
+
+// DefaultHotKeyConfig 默认热 key 统计配置
+func DefaultHotKeyConfig() HotKeyConfig {
+	return HotKeyConfig{
+		Window:     10 * time.Second,
+		SampleRate: 0.1,
+		TopN:       20,
+	}
+}
+
+// HotKey 上一个统计窗口中的热 key，Count 和 QPS 为按采样率换算之后的估计值
+type HotKey struct {
+	Key   string  `json:"key"`
+	Count uint64  `json:"count"`
+	QPS   float64 `json:"qps"`
+}
+
+// countMinSketch 估计每个 key 的访问次数，只会高估不会低估
+type countMinSketch struct {
+	rows [hotKeySketchDepth][hotKeySketchWidth]uint32
+}
+
+// add 增加 key 的计数，返回增加之后的估计值
+func (s *countMinSketch) add(key string) uint32 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := uint32(sum), uint32(sum>>32)
+	var min uint32
+	for i := range s.rows {
+		idx := (h1 + uint32(i)*h2) % hotKeySketchWidth
+		s.rows[i][idx]++
+		if i == 0 || s.rows[i][idx] < min {
+			min = s.rows[i][idx]
+		}
+	}
+	return min
+}
+
+// hotKeyDetector 采样命令中的 key，每个窗口通过 count-min sketch 和 top n 统计热 key
+type hotKeyDetector struct {
+	name    string
+	config  HotKeyConfig
+	locator *keyLocator
+	now     func() time.Time
+
+	mu     sync.Mutex
+	start  time.Time
+	sketch *countMinSketch
+	counts map[string]uint32 // 当前窗口的 top n 候选
+	last   []HotKey          // 上一个窗口的 top n
+}
+
+func newHotKeyDetector(compName string, config HotKeyConfig, locator *keyLocator) *hotKeyDetector {
+	if config.Window <= 0 {
+		config.Window = DefaultHotKeyConfig().Window
+	}
+	if config.TopN <= 0 {
+		config.TopN = DefaultHotKeyConfig().TopN
+	}
+	d := &hotKeyDetector{
+		name:    compName,
+		config:  config,
+		locator: locator,
+		now:     time.Now,
+		sketch:  &countMinSketch{},
+		counts:  make(map[string]uint32),
+	}
+	d.start = d.now()
+	return d
+}
+
+// sample 按采样率记录命令中的 key
+func (d *hotKeyDetector) sample(cmds ...redis.Cmder) {
+	for _, cmd := range cmds {
+		if rand.Float64() >= d.config.SampleRate {
+			continue
+		}
+		for _, key := range d.locator.keys(cmd) {
+			d.record(key)
+		}
+	}
+}
+
+// record 记录一次 key 访问
+func (d *hotKeyDetector) record(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rotate()
+	count := d.sketch.add(key)
+	if _, ok := d.counts[key]; ok || len(d.counts) < d.config.TopN {
+		d.counts[key] = count
+		return
+	}
+	// 替换计数最小的候选
+	minKey, minCount := "", count
+	for k, c := range d.counts {
+		if c < minCount {
+			minKey, minCount = k, c
+		}
+	}
+	if minKey != "" {
+		delete(d.counts, minKey)
+		d.counts[key] = count
+	}
+}
+
+// rotate 窗口结束时生成 top n，并更新监控
+func (d *hotKeyDetector) rotate() {
+	now := d.now()
+	elapsed := now.Sub(d.start)
+	if elapsed < d.config.Window {
+		return
+	}
+
+	for _, hk := range d.last {
+		hotKeyGauge.DeleteLabelValues(d.name, hk.Key)
+	}
+	// 超过两个窗口没有访问时，上一个窗口为空
+	var last []HotKey
+	if elapsed < 2*d.config.Window {
+		last = make([]HotKey, 0, len(d.counts))
+		for key, count := range d.counts {
+			total := float64(count) / d.config.SampleRate
+			last = append(last, HotKey{Key: key, Count: uint64(total), QPS: total / elapsed.Seconds()})
+		}
+		sort.Slice(last, func(i, j int) bool { return last[i].Count > last[j].Count })
+	}
+	for _, hk := range last {
+		hotKeyGauge.Set(hk.QPS, d.name, hk.Key)
+	}
+
+	d.last = last
+	d.start = now
+	d.sketch = &countMinSketch{}
+	d.counts = make(map[string]uint32, d.config.TopN)
+}
+
+// top 返回上一个窗口的热 key，按访问次数从大到小排序
+func (d *hotKeyDetector) top() []HotKey {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rotate()
+	return append([]HotKey(nil), d.last...)
+}
+
+// hotKeyInterceptor 热 key 统计拦截器
+func hotKeyInterceptor(d *hotKeyDetector) *interceptor {
+	return newInterceptor(d.name, nil, nil).
+		setBeforeProcess(func(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+			d.sample(cmd)
+			return ctx, nil
+		}).
+		setBeforeProcessPipeline(func(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+			d.sample(cmds...)
+			return ctx, nil
+		})
+}
+
+// HotKeys 返回上一个统计窗口中的热 key，没有开启热 key 统计时返回 nil
+func (r *Component) HotKeys() []HotKey {
+	if r.hotKeys == nil {
+		return nil
+	}
+	return r.hotKeys.top()
+}
@@ -0,0 +1,56 @@
+package eredis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHotKeyDetector(t *testing.T) {
+	ctx := context.Background()
+	locator := newTestLocator(commandInfos{
+		"get":  {FirstKeyPos: 1, LastKeyPos: 1, StepCount: 1},
+		"mget": {FirstKeyPos: 1, LastKeyPos: -1, StepCount: 1},
+		"ping": {},
+	})
+	now := time.Now()
+	d := newHotKeyDetector("test", HotKeyConfig{Window: 10 * time.Second, SampleRate: 1, TopN: 2}, locator)
+	d.now, d.start = func() time.Time { return now }, now
+
+	for i := 0; i < 100; i++ {
+		d.sample(redis.NewCmd(ctx, "get", "hot"), redis.NewCmd(ctx, "ping"))
+		d.sample(redis.NewCmd(ctx, "get", fmt.Sprintf("cold:%d", i)))
+		if i%2 == 0 {
+			d.sample(redis.NewCmd(ctx, "mget", "warm", "hot"))
+		}
+	}
+	// 窗口结束之前没有结果
+	assert.Empty(t, d.top())
+
+	now = now.Add(10 * time.Second)
+	top := d.top()
+	assert.Len(t, top, 2)
+	assert.Equal(t, "hot", top[0].Key)
+	assert.GreaterOrEqual(t, top[0].Count, uint64(150))
+	assert.InDelta(t, float64(top[0].Count)/10, top[0].QPS, 0.001)
+	assert.Equal(t, "warm", top[1].Key)
+
+	// 超过两个窗口没有访问
+	now = now.Add(20 * time.Second)
+	assert.Empty(t, d.top())
+}
+
+func TestBuildWithHotKeyInterceptor(t *testing.T) {
+	// 启动时的 PING 一定会被采样，此时客户端还没有创建
+	comp := buildUnreachable(t, "redis.hotkey", `
+	enableHotKeyInterceptor = true
+	[redis.hotkey.hotKey]
+		sampleRate = 1`)
+	assert.NotNil(t, comp.hotKeys)
+	assert.Equal(t, float64(1), comp.hotKeys.config.SampleRate)
+	comp.hotKeys.sample(redis.NewCmd(context.Background(), "get", "a"))
+}
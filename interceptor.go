@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
@@ -72,6 +73,10 @@ func (i *interceptor) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
 
 		// 调用下一个 hook 或实际的命令执行
 		err := next(ctx, cmd)
+		// go-redis 在所有 hook 返回之后才调用 cmd.SetErr，提前设置保证 afterProcess 能拿到错误
+		if err != nil && cmd.Err() == nil {
+			cmd.SetErr(err)
+		}
 
 		// AfterProcess 逻辑
 		if i.afterProcess != nil {
@@ -148,6 +153,8 @@ func (i *interceptor) setAfterProcessPipeline(p func(ctx context.Context, cmds [
 }
 
 func fixedInterceptor(compName string, config *config, logger *elog.Component) *interceptor {
+	addr := config.AddrString()
+
 	return newInterceptor(compName, config, logger).
 		setBeforeProcess(func(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
 			return context.WithValue(ctx, ctxBegKey, time.Now()), nil
@@ -157,7 +164,7 @@ func fixedInterceptor(compName string, config *config, logger *elog.Component) *
 			// go-redis script的error做了prefix处理
 			// https://github.com/go-redis/redis/blob/master/script.go#L61
 			if err != nil && !strings.HasPrefix(err.Error(), "NOSCRIPT ") {
-				err = fmt.Errorf("eredis exec command %s fail, %w", cmd.Name(), err)
+				err = &Error{Cmd: cmd.Name(), Node: addr, Err: err}
 			}
 			return err
 		})
@@ -296,7 +303,7 @@ func traceInterceptor(compName string, config *config, logger *elog.Component) *
 		func(ctx context.Context, cmd redis.Cmder) error {
 			span := trace.SpanFromContext(ctx)
 
-			if err := cmd.Err(); err != nil && err != redis.Nil {
+			if err := cmd.Err(); err != nil && !IsNil(err) {
 				span.RecordError(err)
 				span.SetStatus(codes.Error, err.Error())
 			}
@@ -346,14 +353,21 @@ func peerInfo(addr string) (hostname string, port int) {
 	return hostname, port
 }
 
+// packageDir eredis 包所在的目录，fileWithLineNum 跳过该目录下的非测试文件
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// fileWithLineNum 返回调用 eredis 的业务代码位置，跳过 eredis 包和 go-redis 内部的调用栈
 func fileWithLineNum() string {
 	// the second caller usually from internal, so set i start from 2
-	for i := 2; i < 15; i++ {
+	for i := 2; i < 30; i++ {
 		_, file, line, ok := runtime.Caller(i)
 		if !ok {
 			break
 		}
-		if (!(strings.Contains(file, "ego-component/eredis") && strings.HasSuffix(file, "interceptor.go")) && !(strings.Contains(file, "ego-component/eredis") && strings.HasSuffix(file, "comopnent_cmds.go")) && !strings.Contains(file, "go-redis/redis")) || strings.HasSuffix(file, "_test.go") {
+		if strings.HasSuffix(file, "_test.go") || (filepath.Dir(file) != packageDir && !strings.Contains(file, "/go-redis/")) {
 			return file + ":" + strconv.FormatInt(int64(line), 10)
 		}
 	}
@@ -0,0 +1,313 @@
+package eredis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/redis/go-redis/v9"
+)
+
+type keyPrefixCtxKey struct{}
+
+// withoutKeyPrefix 跳过 key 前缀拦截器，用于已经手动处理前缀的内部调用
+func withoutKeyPrefix(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keyPrefixCtxKey{}, true)
+}
+
+func skipKeyPrefix(ctx context.Context) bool {
+	skip, _ := ctx.Value(keyPrefixCtxKey{}).(bool)
+	return skip
+}
+
+// keyLocator 解析命令参数中 key 的位置。
+// 普通命令使用 COMMAND 返回的 first key、last key、step，EVAL、XREAD 等 key 位置不固定的命令单独解析。
+// 客户端创建之前（启动时的 PING）或者 COMMAND 不可用（例如被 ACL 禁止）时使用内置的 key 位置。
+type keyLocator struct {
+	logger *elog.Component
+
+	client atomic.Value // redis.Cmdable，客户端创建之后设置
+	once   sync.Once
+	infos  atomic.Value // commandInfos，只加载一次
+}
+
+type commandInfos map[string]*redis.CommandInfo
+
+// keyPrefixer 根据命令的 key 位置为所有 key 加上前缀
+type keyPrefixer struct {
+	prefix string
+	*keyLocator
+}
+
+// setClient 客户端创建之后设置，之后首次解析 key 位置时加载 COMMAND
+func (p *keyLocator) setClient(client redis.Cmdable) {
+	p.client.Store(client)
+}
+
+// commandInfo 返回 COMMAND 中命令的 key 元数据，COMMAND 不可用时 ok 为 false
+func (p *keyLocator) commandInfo(name string) (*redis.CommandInfo, bool) {
+	infos, loaded := p.infos.Load().(commandInfos)
+	if !loaded {
+		client, _ := p.client.Load().(redis.Cmdable)
+		if client == nil {
+			return nil, false
+		}
+		p.once.Do(func() { p.load(client) })
+		infos, _ = p.infos.Load().(commandInfos)
+	}
+	if infos == nil {
+		return nil, false
+	}
+	return infos[name], true
+}
+
+// load 加载 COMMAND，失败时记录日志并一直使用内置的 key 位置
+func (p *keyLocator) load(client redis.Cmdable) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	infos, err := client.Command(ctx).Result()
+	if err != nil {
+		p.logger.Warn("load command info fail, use builtin key positions", elog.FieldErr(err))
+		p.infos.Store(commandInfos(nil))
+		return
+	}
+	p.infos.Store(commandInfos(infos))
+}
+
+// apply 为命令的 key 加上前缀
+func (p *keyPrefixer) apply(cmd redis.Cmder) {
+	args := cmd.Args()
+	name := strings.ToLower(cmd.Name())
+	switch name {
+	case "scan":
+		for i := 2; i+1 < len(args); i++ {
+			if strings.EqualFold(fmt.Sprint(args[i]), "match") {
+				args[i+1] = escapeGlob(p.prefix) + fmt.Sprint(args[i+1])
+				break
+			}
+		}
+		return
+	case "keys":
+		if len(args) > 1 {
+			args[1] = escapeGlob(p.prefix) + fmt.Sprint(args[1])
+		}
+		return
+	}
+
+	for _, pos := range p.keyPositions(name, args) {
+		switch key := args[pos].(type) {
+		case string:
+			args[pos] = p.prefix + key
+		case []byte:
+			args[pos] = append([]byte(p.prefix), key...)
+		default:
+			args[pos] = p.prefix + fmt.Sprint(key)
+		}
+	}
+}
+
+// strip 去掉 SCAN、KEYS 返回结果中的前缀，SCAN 没有指定 MATCH 时过滤掉其他前缀的 key
+func (p *keyPrefixer) strip(cmd redis.Cmder) {
+	switch c := cmd.(type) {
+	case *redis.ScanCmd:
+		if c.Name() != "scan" {
+			return
+		}
+		page, cursor := c.Val()
+		c.SetVal(p.stripKeys(page), cursor)
+	case *redis.StringSliceCmd:
+		if c.Name() == "keys" {
+			c.SetVal(p.stripKeys(c.Val()))
+		}
+	}
+}
+
+func (p *keyPrefixer) stripKeys(keys []string) []string {
+	ret := keys[:0]
+	for _, key := range keys {
+		if strings.HasPrefix(key, p.prefix) {
+			ret = append(ret, key[len(p.prefix):])
+		}
+	}
+	return ret
+}
+
+// keys 返回命令中的 key
+func (p *keyLocator) keys(cmd redis.Cmder) []string {
+	args := cmd.Args()
+	positions := p.keyPositions(strings.ToLower(cmd.Name()), args)
+	if len(positions) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(positions))
+	for _, pos := range positions {
+		keys = append(keys, fmt.Sprint(args[pos]))
+	}
+	return keys
+}
+
+// keyPositions 返回命令参数中 key 的位置
+func (p *keyLocator) keyPositions(name string, args []interface{}) []int {
+	switch name {
+	case "command", "publish", "spublish", "subscribe", "ssubscribe", "unsubscribe", "sunsubscribe":
+		// channel 不是 key，Redis 7 的 COMMAND 会把 SPUBLISH、SSUBSCRIBE 的 channel 作为 key 返回
+		return nil
+	case "eval", "evalsha", "eval_ro", "evalsha_ro", "fcall", "fcall_ro", "blmpop", "bzmpop":
+		return numKeys(args, 2)
+	case "zunion", "zinter", "zdiff", "sintercard", "zintercard", "lmpop", "zmpop":
+		return numKeys(args, 1)
+	case "zunionstore", "zinterstore", "zdiffstore":
+		return append([]int{1}, numKeys(args, 2)...)
+	case "xread", "xreadgroup":
+		for i := 1; i < len(args); i++ {
+			if strings.EqualFold(fmt.Sprint(args[i]), "streams") {
+				n := (len(args) - i - 1) / 2
+				return positionRange(i+1, i+n, 1)
+			}
+		}
+		return nil
+	case "georadius", "georadiusbymember", "sort", "sort_ro":
+		positions := []int{1}
+		for i := 2; i+1 < len(args); i++ {
+			if opt := strings.ToLower(fmt.Sprint(args[i])); opt == "store" || opt == "storedist" {
+				positions = append(positions, i+1)
+			}
+		}
+		return positions
+	case "object", "memory", "xinfo", "xgroup":
+		// 子命令的第一个参数为 key
+		if len(args) > 2 && !strings.EqualFold(fmt.Sprint(args[1]), "help") {
+			return []int{2}
+		}
+		return nil
+	}
+
+	first, last, step := 0, 0, 0
+	if info, ok := p.commandInfo(name); ok {
+		if info != nil {
+			first, last, step = int(info.FirstKeyPos), int(info.LastKeyPos), int(info.StepCount)
+		}
+	} else if spec, ok := builtinKeySpecs[name]; ok {
+		first, last, step = spec[0], spec[1], spec[2]
+	}
+	if first <= 0 {
+		return nil
+	}
+	if last < 0 {
+		last += len(args)
+	}
+	return positionRange(first, last, step)
+}
+
+// builtinKeySpecs COMMAND 不可用时使用的 key 位置：first key、last key、step，last 为负数时从末尾计算
+var builtinKeySpecs = func() map[string][3]int {
+	specs := make(map[string][3]int)
+	add := func(spec [3]int, names ...string) {
+		for _, name := range names {
+			specs[name] = spec
+		}
+	}
+	add([3]int{1, 1, 1},
+		"get", "set", "setnx", "setex", "psetex", "getset", "getdel", "getex", "append", "strlen", "getrange", "setrange",
+		"incr", "decr", "incrby", "decrby", "incrbyfloat", "setbit", "getbit", "bitcount", "bitpos", "bitfield",
+		"expire", "pexpire", "expireat", "pexpireat", "expiretime", "pexpiretime", "ttl", "pttl", "persist", "type", "dump", "restore",
+		"hset", "hsetnx", "hget", "hmget", "hmset", "hdel", "hgetall", "hexists", "hincrby", "hincrbyfloat", "hkeys", "hvals", "hlen", "hstrlen", "hrandfield", "hscan",
+		"lpush", "rpush", "lpushx", "rpushx", "lpop", "rpop", "llen", "lrange", "lrem", "lset", "lindex", "ltrim", "linsert", "lpos",
+		"sadd", "srem", "smembers", "sismember", "smismember", "scard", "spop", "srandmember", "sscan",
+		"zadd", "zrem", "zscore", "zmscore", "zincrby", "zcard", "zcount", "zlexcount", "zrange", "zrevrange", "zrangebyscore", "zrevrangebyscore",
+		"zrangebylex", "zrevrangebylex", "zrank", "zrevrank", "zremrangebyrank", "zremrangebyscore", "zremrangebylex", "zpopmin", "zpopmax", "zrandmember", "zscan",
+		"pfadd", "geoadd", "geodist", "geopos", "geohash", "geosearch",
+		"xadd", "xlen", "xrange", "xrevrange", "xdel", "xtrim", "xack", "xpending", "xclaim", "xautoclaim", "xsetid",
+	)
+	add([3]int{1, 2, 1}, "rpoplpush", "brpoplpush", "lmove", "blmove", "smove", "rename", "renamenx", "copy", "zrangestore", "geosearchstore", "lcs")
+	add([3]int{1, -1, 1}, "del", "unlink", "exists", "touch", "watch", "mget", "pfcount", "pfmerge",
+		"sunion", "sinter", "sdiff", "sunionstore", "sinterstore", "sdiffstore")
+	add([3]int{1, -2, 1}, "blpop", "brpop", "bzpopmin", "bzpopmax")
+	add([3]int{1, -1, 2}, "mset", "msetnx")
+	add([3]int{2, -1, 1}, "bitop")
+	return specs
+}()
+
+// numKeys 解析 numkeys 参数，返回之后 numkeys 个 key 的位置
+func numKeys(args []interface{}, pos int) []int {
+	if pos >= len(args) {
+		return nil
+	}
+	n, err := strconv.Atoi(fmt.Sprint(args[pos]))
+	if err != nil || n <= 0 {
+		return nil
+	}
+	return positionRange(pos+1, pos+n, 1)
+}
+
+func positionRange(first, last, step int) []int {
+	if step <= 0 {
+		step = 1
+	}
+	positions := make([]int, 0)
+	for i := first; i <= last; i += step {
+		positions = append(positions, i)
+	}
+	return positions
+}
+
+// escapeGlob 转义 glob 特殊字符，保证前缀按字面量匹配
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// keyPrefixInterceptor key 前缀拦截器
+func keyPrefixInterceptor(p *keyPrefixer) *interceptor {
+	return newInterceptor("", nil, p.logger).
+		setBeforeProcess(func(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+			if skipKeyPrefix(ctx) {
+				return ctx, nil
+			}
+			p.apply(cmd)
+			return ctx, nil
+		}).
+		setAfterProcess(func(ctx context.Context, cmd redis.Cmder) error {
+			if !skipKeyPrefix(ctx) {
+				p.strip(cmd)
+			}
+			return nil
+		}).
+		setBeforeProcessPipeline(func(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+			if skipKeyPrefix(ctx) {
+				return ctx, nil
+			}
+			for _, cmd := range cmds {
+				p.apply(cmd)
+			}
+			return ctx, nil
+		}).
+		setAfterProcessPipeline(func(ctx context.Context, cmds []redis.Cmder) error {
+			if !skipKeyPrefix(ctx) {
+				for _, cmd := range cmds {
+					p.strip(cmd)
+				}
+			}
+			return nil
+		})
+}
+
+// stripKeyPrefix 去掉 redis 返回的 key 中的全局前缀，不带前缀时 ok 为 false
+func (r *Component) stripKeyPrefix(key string) (string, bool) {
+	if !strings.HasPrefix(key, r.config.KeyPrefix) {
+		return key, false
+	}
+	return key[len(r.config.KeyPrefix):], true
+}
@@ -0,0 +1,125 @@
+package eredis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/gotomicro/ego/core/elog"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyPrefixApply(t *testing.T) {
+	ctx := context.Background()
+	p := &keyPrefixer{prefix: "svc:", keyLocator: newTestLocator(commandInfos{
+		"get":      {FirstKeyPos: 1, LastKeyPos: 1, StepCount: 1},
+		"mget":     {FirstKeyPos: 1, LastKeyPos: -1, StepCount: 1},
+		"mset":     {FirstKeyPos: 1, LastKeyPos: -1, StepCount: 2},
+		"publish":  {FirstKeyPos: 0, LastKeyPos: 0, StepCount: 0},
+		"spublish": {FirstKeyPos: 1, LastKeyPos: 1, StepCount: 1},
+	})}
+
+	cases := []struct {
+		args []interface{}
+		want []interface{}
+	}{
+		{[]interface{}{"get", "a"}, []interface{}{"get", "svc:a"}},
+		{[]interface{}{"mget", "a", "b"}, []interface{}{"mget", "svc:a", "svc:b"}},
+		{[]interface{}{"mset", "a", "1", "b", "2"}, []interface{}{"mset", "svc:a", "1", "svc:b", "2"}},
+		{[]interface{}{"publish", "ch", "msg"}, []interface{}{"publish", "ch", "msg"}},
+		{[]interface{}{"spublish", "ch", "msg"}, []interface{}{"spublish", "ch", "msg"}},
+		{[]interface{}{"evalsha", "sha", 2, "a", "b", "arg"}, []interface{}{"evalsha", "sha", 2, "svc:a", "svc:b", "arg"}},
+		{[]interface{}{"zunionstore", "dst", 2, "a", "b", "weights", 1, 2}, []interface{}{"zunionstore", "svc:dst", 2, "svc:a", "svc:b", "weights", 1, 2}},
+		{[]interface{}{"xreadgroup", "group", "g", "c", "count", 1, "streams", "s1", "s2", ">", ">"}, []interface{}{"xreadgroup", "group", "g", "c", "count", 1, "streams", "svc:s1", "svc:s2", ">", ">"}},
+		{[]interface{}{"georadius", "geo", 1.0, 2.0, 10, "km", "store", "dst"}, []interface{}{"georadius", "svc:geo", 1.0, 2.0, 10, "km", "store", "svc:dst"}},
+		{[]interface{}{"memory", "usage", "a"}, []interface{}{"memory", "usage", "svc:a"}},
+		{[]interface{}{"scan", 0, "match", "user:*", "count", 10}, []interface{}{"scan", 0, "match", "svc:user:*", "count", 10}},
+		{[]interface{}{"keys", "*"}, []interface{}{"keys", "svc:*"}},
+	}
+	for _, c := range cases {
+		cmd := redis.NewCmd(ctx, c.args...)
+		p.apply(cmd)
+		assert.Equal(t, c.want, cmd.Args())
+	}
+
+	scan := redis.NewScanCmd(ctx, nil, "scan", 0)
+	scan.SetVal([]string{"svc:a", "other:b", "svc:c"}, 12)
+	p.strip(scan)
+	page, cursor := scan.Val()
+	assert.Equal(t, []string{"a", "c"}, page)
+	assert.Equal(t, uint64(12), cursor)
+
+	keys := redis.NewStringSliceCmd(ctx, "keys", "svc:*")
+	keys.SetVal([]string{"svc:a"})
+	p.strip(keys)
+	assert.Equal(t, []string{"a"}, keys.Val())
+}
+
+func TestKeyPrefixBuiltinPositions(t *testing.T) {
+	ctx := context.Background()
+	// 客户端创建之前以及 COMMAND 不可用时使用内置的 key 位置
+	p := &keyPrefixer{prefix: "svc:", keyLocator: &keyLocator{logger: elog.DefaultLogger}}
+	cases := []struct {
+		args []interface{}
+		want []interface{}
+	}{
+		{[]interface{}{"ping"}, []interface{}{"ping"}},
+		{[]interface{}{"get", "a"}, []interface{}{"get", "svc:a"}},
+		{[]interface{}{"mset", "a", "1", "b", "2"}, []interface{}{"mset", "svc:a", "1", "svc:b", "2"}},
+		{[]interface{}{"blmove", "a", "b", "right", "left", 1}, []interface{}{"blmove", "svc:a", "svc:b", "right", "left", 1}},
+		{[]interface{}{"bzpopmin", "a", "b", 1}, []interface{}{"bzpopmin", "svc:a", "svc:b", 1}},
+	}
+	for _, c := range cases {
+		cmd := redis.NewCmd(ctx, c.args...)
+		p.apply(cmd)
+		assert.Equal(t, c.want, cmd.Args())
+	}
+
+	// COMMAND 加载失败之后不再重试，也不返回错误
+	p.setClient(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond}))
+	cmd := redis.NewCmd(ctx, "hget", "h", "f")
+	p.apply(cmd)
+	assert.Equal(t, []interface{}{"hget", "svc:h", "f"}, cmd.Args())
+	_, ok := p.commandInfo("hget")
+	assert.False(t, ok)
+}
+
+func TestBuildWithKeyPrefix(t *testing.T) {
+	// 启动时的 PING 经过 key 前缀拦截器，此时客户端还没有创建
+	comp := buildUnreachable(t, "redis.prefix", `keyPrefix = "svc:"`)
+	assert.Equal(t, "svc:", comp.config.KeyPrefix)
+}
+
+// buildUnreachable 使用不可连接的地址构建组件，只验证构建过程
+func buildUnreachable(t *testing.T, name string, extra string) *Component {
+	conf := fmt.Sprintf(`
+[%s]
+	addr = "127.0.0.1:1"
+	dialTimeout = "100ms"
+	maxRetries = -1
+	onFail = "error"
+	%s
+`, name, extra)
+	assert.NoError(t, econf.LoadFromReader(strings.NewReader(conf), toml.Unmarshal))
+	return Load(name).Build()
+}
+
+func newTestLocator(infos commandInfos) *keyLocator {
+	l := &keyLocator{logger: elog.DefaultLogger}
+	l.infos.Store(infos)
+	return l
+}
+
+func TestKeyPrefixSlot(t *testing.T) {
+	assert.Equal(t, `a\*\?\[b\]\\`, escapeGlob(`a*?[b]\`))
+
+	// 按加上前缀之后的 slot 分组
+	groups := groupKeysBySlot("{svc}:", []string{"a", "b", "c"})
+	assert.Len(t, groups, 1)
+}
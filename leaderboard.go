@@ -0,0 +1,432 @@
+package eredis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LeaderboardMode 提交分数的方式
+type LeaderboardMode string
+
+const (
+	// LeaderboardBest 保留最高分
+	LeaderboardBest LeaderboardMode = "best"
+	// LeaderboardLatest 保留最近一次提交的分数
+	LeaderboardLatest LeaderboardMode = "latest"
+	// LeaderboardIncrement 累加分数
+	LeaderboardIncrement LeaderboardMode = "increment"
+)
+
+// LeaderboardPeriod 排行榜的周期
+type LeaderboardPeriod string
+
+const (
+	// LeaderboardAllTime 不分周期
+	LeaderboardAllTime LeaderboardPeriod = ""
+	// LeaderboardDaily 每天一个排行榜
+	LeaderboardDaily LeaderboardPeriod = "d"
+	// LeaderboardWeekly 每周一个排行榜，周一开始
+	LeaderboardWeekly LeaderboardPeriod = "w"
+)
+
+const (
+	// leaderboardAllTimeSpan 不分周期的排行榜 tie-break 时间部分覆盖的秒数，时间部分占用 31 位
+	leaderboardAllTimeSpan = (1<<31 - 1) * time.Second
+	// MaxLeaderboardScore 开启 tie-break 时所有周期都可以使用的分数绝对值上限，即不分周期的排行榜的上限，
+	// 周期排行榜的时间部分占用的位数更少，上限见 Leaderboard.MaxScore
+	MaxLeaderboardScore = 1<<(53-31) - 1
+)
+
+// leaderboardEpoch 不分周期的排行榜计算 tie-break 时间的起点
+var leaderboardEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	// luaLeaderboardSubmit 按照提交方式计算新的分数，返回新的分数，分数超过上限时返回错误
+	luaLeaderboardSubmit = redis.NewScript(`
+local tiebreak = ARGV[4] == "1"
+local shift = tonumber(ARGV[7])
+local v = tonumber(ARGV[2])
+local old = redis.call("zscore", KEYS[1], ARGV[5])
+local oldv
+if old then
+  oldv = tonumber(old)
+  if tiebreak then oldv = math.floor(oldv / shift) end
+end
+if ARGV[1] == "increment" then
+  v = (oldv or 0) + v
+elseif ARGV[1] == "best" and oldv and oldv >= v then
+  return oldv
+end
+local score = v
+if tiebreak then
+  if math.abs(v) > tonumber(ARGV[8]) then return redis.error_reply("ERR leaderboard score out of range") end
+  score = v * shift + tonumber(ARGV[3])
+end
+redis.call("zadd", KEYS[1], string.format("%.0f", score), ARGV[5])
+if tonumber(ARGV[6]) > 0 then redis.call("pexpire", KEYS[1], ARGV[6]) end
+return v`)
+
+	// luaLeaderboardRollup 开启 tie-break 时汇总周期排行榜。
+	// 按照源排行榜的位数取出分数和提交时间，increment 方式累加分数并使用最后一次提交的时间，
+	// 其他方式取最高分并使用先达到该分数的时间，再按照目标排行榜的位数写入 KEYS[1]
+	luaLeaderboardRollup = redis.NewScript(`
+local sum = ARGV[1] == "SUM"
+local sshift, slimit = tonumber(ARGV[2]), tonumber(ARGV[3])
+local dshift, dlimit, dstart = tonumber(ARGV[4]), tonumber(ARGV[5]), tonumber(ARGV[6])
+local max = tonumber(ARGV[7])
+local agg, members = {}, {}
+for i = 2, #KEYS do
+  local start = tonumber(ARGV[7 + i])
+  local zs = redis.call("zrange", KEYS[i], 0, -1, "WITHSCORES")
+  for j = 1, #zs, 2 do
+    local score = tonumber(zs[j + 1])
+    local v = math.floor(score / sshift)
+    local t = start + slimit - 1 - (score - v * sshift)
+    local cur = agg[zs[j]]
+    if not cur then
+      agg[zs[j]] = {v, t}
+      members[#members + 1] = zs[j]
+    elseif sum then
+      cur[1] = cur[1] + v
+      if t > cur[2] then cur[2] = t end
+    elseif v > cur[1] or (v == cur[1] and t < cur[2]) then
+      cur[1], cur[2] = v, t
+    end
+  end
+end
+for _, m in ipairs(members) do
+  if math.abs(agg[m][1]) > max then return redis.error_reply("ERR leaderboard score out of range") end
+end
+redis.call("del", KEYS[1])
+for _, m in ipairs(members) do
+  local part = dlimit - 1 - (agg[m][2] - dstart)
+  if part < 0 then part = 0 elseif part > dlimit - 1 then part = dlimit - 1 end
+  redis.call("zadd", KEYS[1], string.format("%.0f", agg[m][1] * dshift + part), m)
+end
+if #members > 0 and tonumber(ARGV[8]) > 0 then redis.call("pexpire", KEYS[1], ARGV[8]) end
+return #members`)
+)
+
+// LeaderboardEntry 排行榜中的成员
+type LeaderboardEntry struct {
+	Member string `json:"member"`
+	Score  int64  `json:"score"`
+	Rank   int64  `json:"rank"` // Rank 排名，从 1 开始
+}
+
+// LeaderboardOption 排行榜选项
+type LeaderboardOption func(l *Leaderboard)
+
+// WithLeaderboardMode 提交分数的方式，默认 LeaderboardBest
+func WithLeaderboardMode(mode LeaderboardMode) LeaderboardOption {
+	return func(l *Leaderboard) {
+		l.mode = mode
+	}
+}
+
+// WithLeaderboardPeriod 排行榜的周期，默认 LeaderboardAllTime
+func WithLeaderboardPeriod(period LeaderboardPeriod) LeaderboardOption {
+	return func(l *Leaderboard) {
+		l.period = period
+	}
+}
+
+// WithLeaderboardTieBreak 分数相同时先达到该分数的成员排名靠前，默认开启。
+// 开启时分数绝对值不能超过 MaxScore
+func WithLeaderboardTieBreak(enable bool) LeaderboardOption {
+	return func(l *Leaderboard) {
+		l.tieBreak = enable
+	}
+}
+
+// WithLeaderboardTTL 每次提交之后设置排行榜的过期时间，周期排行榜用于自动清理历史数据，默认不过期
+func WithLeaderboardTTL(ttl time.Duration) LeaderboardOption {
+	return func(l *Leaderboard) {
+		l.ttl = ttl
+	}
+}
+
+// WithLeaderboardLocation 划分周期使用的时区，默认 time.Local
+func WithLeaderboardLocation(loc *time.Location) LeaderboardOption {
+	return func(l *Leaderboard) {
+		l.loc = loc
+	}
+}
+
+// Leaderboard 基于 ZSET 的排行榜，由 NewLeaderboard 创建。
+// 所有周期的 key 使用 {name} 作为 hash tag，cluster 模式下位于同一个 slot：
+//
+//	{name}               不分周期
+//	{name}:d:20060102    每天
+//	{name}:w:2006-01     每周，ISO 周
+type Leaderboard struct {
+	comp     *Component
+	name     string
+	mode     LeaderboardMode
+	period   LeaderboardPeriod
+	tieBreak bool
+	ttl      time.Duration
+	loc      *time.Location
+	at       time.Time // at 为零值时使用当前时间所在的周期
+	now      func() time.Time
+}
+
+// NewLeaderboard 创建排行榜
+func (r *Component) NewLeaderboard(name string, opts ...LeaderboardOption) *Leaderboard {
+	l := &Leaderboard{
+		comp:     r,
+		name:     name,
+		mode:     LeaderboardBest,
+		period:   LeaderboardAllTime,
+		tieBreak: true,
+		loc:      time.Local,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.loc == nil {
+		l.loc = time.Local
+	}
+	return l
+}
+
+// At 返回 t 所在周期的排行榜，用于查询历史周期
+func (l *Leaderboard) At(t time.Time) *Leaderboard {
+	c := *l
+	c.at = t
+	return &c
+}
+
+// Key 当前周期的 key
+func (l *Leaderboard) Key() string {
+	t := l.at
+	if t.IsZero() {
+		t = l.now()
+	}
+	return l.keyAt(t)
+}
+
+func (l *Leaderboard) keyAt(t time.Time) string {
+	t = t.In(l.loc)
+	switch l.period {
+	case LeaderboardDaily:
+		return fmt.Sprintf("{%s}:d:%s", l.name, t.Format("20060102"))
+	case LeaderboardWeekly:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("{%s}:w:%d-%02d", l.name, year, week)
+	default:
+		return "{" + l.name + "}"
+	}
+}
+
+// periodStart 返回 t 所在周期的开始时间和周期长度，不分周期时返回 leaderboardEpoch
+func (l *Leaderboard) periodStart(t time.Time) (time.Time, time.Duration) {
+	t = t.In(l.loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, l.loc)
+	switch l.period {
+	case LeaderboardDaily:
+		return day, 24 * time.Hour
+	case LeaderboardWeekly:
+		offset := (int(t.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset), 7 * 24 * time.Hour
+	default:
+		return leaderboardEpoch, leaderboardAllTimeSpan
+	}
+}
+
+// timeLimit tie-break 时间部分的取值个数，即周期内的秒数
+func (l *Leaderboard) timeLimit() int64 {
+	_, span := l.periodStart(leaderboardEpoch)
+	return int64(span / time.Second)
+}
+
+// timeBits tie-break 时间部分占用的位数，由周期长度决定，每天 17 位，每周 20 位，不分周期 31 位
+func (l *Leaderboard) timeBits() int {
+	return bits.Len64(uint64(l.timeLimit() - 1))
+}
+
+// shift 开启 tie-break 时 score = value<<timeBits + 时间部分
+func (l *Leaderboard) shift() int64 {
+	return 1 << l.timeBits()
+}
+
+// MaxScore 开启 tie-break 时分数绝对值的上限，保证 score 在 float64 中精确表示，每天 2^36-1，每周 2^33-1，不分周期 2^22-1
+func (l *Leaderboard) MaxScore() int64 {
+	return 1<<(53-l.timeBits()) - 1
+}
+
+// tieBreakPart 越早提交值越大，使用周期内的秒数
+func (l *Leaderboard) tieBreakPart(t time.Time) int64 {
+	start, _ := l.periodStart(t)
+	limit := l.timeLimit()
+	elapsed := int64(t.Sub(start) / time.Second)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	if elapsed >= limit {
+		elapsed = limit - 1
+	}
+	return limit - 1 - elapsed
+}
+
+// decode 从 ZSET 的 score 中取出分数
+func (l *Leaderboard) decode(score float64) int64 {
+	if l.tieBreak {
+		return int64(math.Floor(score / float64(l.shift())))
+	}
+	return int64(score)
+}
+
+// Submit 按照提交方式提交成员的分数，返回提交之后的分数。
+// 开启 tie-break 时分数或者累加之后的分数绝对值超过 MaxScore 返回错误
+func (l *Leaderboard) Submit(ctx context.Context, member string, score int64) (int64, error) {
+	if l.tieBreak && (score > l.MaxScore() || score < -l.MaxScore()) {
+		return 0, fmt.Errorf("%w: leaderboard score %d out of range", ErrInvalidParams, score)
+	}
+	now := l.now()
+	if !l.at.IsZero() {
+		now = l.at
+	}
+	tieBreak := "0"
+	if l.tieBreak {
+		tieBreak = "1"
+	}
+	args := []interface{}{string(l.mode), score, l.tieBreakPart(now), tieBreak, member, l.ttl.Milliseconds(), l.shift(), l.MaxScore()}
+	v, err := luaLeaderboardSubmit.Run(ctx, l.comp.client, []string{l.keyAt(now)}, args...).Int64()
+	if err != nil {
+		return 0, l.comp.wrapErr("evalsha", err)
+	}
+	return v, nil
+}
+
+// Get 查询成员的分数和排名，成员不存在时返回 nil
+func (l *Leaderboard) Get(ctx context.Context, member string) (*LeaderboardEntry, error) {
+	key := l.Key()
+	var score *redis.FloatCmd
+	var rank *redis.IntCmd
+	_, err := l.comp.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		score = pipe.ZScore(ctx, key, member)
+		rank = pipe.ZRevRank(ctx, key, member)
+		return nil
+	})
+	if IsNil(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, l.comp.wrapErr("zscore", err)
+	}
+	return &LeaderboardEntry{Member: member, Score: l.decode(score.Val()), Rank: rank.Val() + 1}, nil
+}
+
+// Top 分页查询排名，offset 从 0 开始
+func (l *Leaderboard) Top(ctx context.Context, offset, limit int64) ([]LeaderboardEntry, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	return l.rangeByRank(ctx, offset, offset+limit-1)
+}
+
+// Around 查询成员以及排名在其前后各 n 名的成员，成员不存在时返回 nil
+func (l *Leaderboard) Around(ctx context.Context, member string, n int64) ([]LeaderboardEntry, error) {
+	rank, err := l.comp.client.ZRevRank(ctx, l.Key(), member).Result()
+	if IsNil(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, l.comp.wrapErr("zrevrank", err)
+	}
+	start := rank - n
+	if start < 0 {
+		start = 0
+	}
+	return l.rangeByRank(ctx, start, rank+n)
+}
+
+func (l *Leaderboard) rangeByRank(ctx context.Context, start, stop int64) ([]LeaderboardEntry, error) {
+	zs, err := l.comp.client.ZRevRangeWithScores(ctx, l.Key(), start, stop).Result()
+	if err != nil {
+		return nil, l.comp.wrapErr("zrevrange", err)
+	}
+	entries := make([]LeaderboardEntry, 0, len(zs))
+	for i, z := range zs {
+		member, _ := z.Member.(string)
+		entries = append(entries, LeaderboardEntry{Member: member, Score: l.decode(z.Score), Rank: start + int64(i) + 1})
+	}
+	return entries, nil
+}
+
+// Count 排行榜中的成员数量
+func (l *Leaderboard) Count(ctx context.Context) (int64, error) {
+	n, err := l.comp.client.ZCard(ctx, l.Key()).Result()
+	return n, l.comp.wrapErr("zcard", err)
+}
+
+// Remove 删除成员
+func (l *Leaderboard) Remove(ctx context.Context, members ...string) (int64, error) {
+	args := make([]interface{}, 0, len(members))
+	for _, member := range members {
+		args = append(args, member)
+	}
+	n, err := l.comp.client.ZRem(ctx, l.Key(), args...).Result()
+	return n, l.comp.wrapErr("zrem", err)
+}
+
+// Rollup 把 [from, to) 之间的周期排行榜汇总到 dst 在 from 所在周期的排行榜，覆盖 dst 原有的数据，
+// 例如把每天的排行榜汇总为每周的排行榜。increment 方式累加分数，其他方式取最高分。
+// 不同周期的 tie-break 时间部分位数不同，开启 tie-break 时在 Lua 脚本中按照 dst 的位数重新计算 score，
+// 源排行榜很大时会长时间占用 Redis，关闭 tie-break 时使用 ZUNIONSTORE。
+// cluster 模式下 dst 需要使用相同的 name，两者的 tie-break 设置需要一致
+func (l *Leaderboard) Rollup(ctx context.Context, dst *Leaderboard, from, to time.Time) (int64, error) {
+	if l.period == LeaderboardAllTime || l.tieBreak != dst.tieBreak {
+		return 0, fmt.Errorf("%w: leaderboard rollup needs periodic source with the same tie-break", ErrInvalidParams)
+	}
+	var (
+		keys   []string
+		starts []interface{}
+	)
+	for t, _ := l.periodStart(from); t.Before(to); {
+		keys = append(keys, l.keyAt(t))
+		starts = append(starts, t.Unix())
+		if l.period == LeaderboardDaily {
+			t = t.AddDate(0, 0, 1)
+		} else {
+			t = t.AddDate(0, 0, 7)
+		}
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	aggregate := "MAX"
+	if l.mode == LeaderboardIncrement {
+		aggregate = "SUM"
+	}
+	dest := dst.keyAt(from)
+	if l.tieBreak {
+		dstStart, _ := dst.periodStart(from)
+		args := append([]interface{}{aggregate, l.shift(), l.timeLimit(), dst.shift(), dst.timeLimit(), dstStart.Unix(),
+			dst.MaxScore(), dst.ttl.Milliseconds()}, starts...)
+		n, err := luaLeaderboardRollup.Run(ctx, l.comp.client, append([]string{dest}, keys...), args...).Int64()
+		if err != nil {
+			return 0, l.comp.wrapErr("evalsha", err)
+		}
+		return n, nil
+	}
+	var n *redis.IntCmd
+	_, err := l.comp.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		n = pipe.ZUnionStore(ctx, dest, &redis.ZStore{Keys: keys, Aggregate: aggregate})
+		if dst.ttl > 0 {
+			pipe.PExpire(ctx, dest, dst.ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, l.comp.wrapErr("zunionstore", err)
+	}
+	return n.Val(), nil
+}
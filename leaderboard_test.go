@@ -0,0 +1,216 @@
+package eredis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderboardKey(t *testing.T) {
+	r := &Component{name: "redis.test", logger: elog.DefaultLogger}
+	// 2026-10-18 是周日
+	now := time.Date(2026, 10, 18, 1, 0, 0, 0, time.UTC)
+	daily := r.NewLeaderboard("game", WithLeaderboardPeriod(LeaderboardDaily), WithLeaderboardLocation(time.UTC))
+	daily.now = func() time.Time { return now }
+	assert.Equal(t, "{game}:d:20261018", daily.Key())
+	assert.Equal(t, "{game}:d:20261017", daily.At(now.Add(-24*time.Hour)).Key())
+	assert.Equal(t, int64(86400-1-3600), daily.tieBreakPart(now))
+
+	weekly := r.NewLeaderboard("game", WithLeaderboardPeriod(LeaderboardWeekly), WithLeaderboardLocation(time.UTC))
+	assert.Equal(t, "{game}:w:2026-42", weekly.At(now).Key())
+	start, _ := weekly.periodStart(now)
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), start)
+
+	// 分数相同时先提交的 score 更大，取出的分数不受时间部分影响
+	early := float64(100*daily.shift() + daily.tieBreakPart(now))
+	late := float64(100*daily.shift() + daily.tieBreakPart(now.Add(time.Hour)))
+	assert.Greater(t, early, late)
+	assert.Equal(t, int64(100), daily.decode(early))
+	assert.Equal(t, int64(-3), daily.decode(float64(-3*daily.shift()+5)))
+
+	plain := r.NewLeaderboard("game", WithLeaderboardTieBreak(false))
+	assert.Equal(t, "{game}", plain.Key())
+	assert.Equal(t, int64(42), plain.decode(42))
+
+	_, err := daily.Submit(context.Background(), "a", daily.MaxScore()+1)
+	assert.True(t, errors.Is(err, ErrInvalidParams))
+	_, err = plain.Rollup(context.Background(), weekly, now, now)
+	assert.True(t, errors.Is(err, ErrInvalidParams))
+}
+
+func TestLeaderboardScoreBoundary(t *testing.T) {
+	r := &Component{name: "redis.test", logger: elog.DefaultLogger}
+	for _, c := range []struct {
+		period LeaderboardPeriod
+		bits   int
+	}{{LeaderboardDaily, 17}, {LeaderboardWeekly, 20}, {LeaderboardAllTime, 31}} {
+		l := r.NewLeaderboard("game", WithLeaderboardPeriod(c.period))
+		assert.Equal(t, c.bits, l.timeBits())
+		assert.Equal(t, int64(1)<<(53-c.bits)-1, l.MaxScore())
+		assert.LessOrEqual(t, int64(MaxLeaderboardScore), l.MaxScore())
+		assert.Less(t, l.timeLimit()-1, l.shift())
+
+		// 上限附近的分数和时间部分在 float64 中精确表示，并且保持排序
+		for _, v := range []int64{l.MaxScore(), l.MaxScore() - 1, -l.MaxScore(), -l.MaxScore() + 1} {
+			for _, part := range []int64{0, l.timeLimit() - 1} {
+				score := v*l.shift() + part
+				assert.Equal(t, score, int64(float64(score)), "period %q value %d part %d", c.period, v, part)
+				assert.Equal(t, v, l.decode(float64(score)))
+			}
+		}
+		assert.Less(t, float64((l.MaxScore()-1)*l.shift()+l.timeLimit()-1), float64(l.MaxScore()*l.shift()))
+	}
+}
+
+func TestLeaderboardSubmit(t *testing.T) {
+	comp := newTestRedis(t, "redis.leaderboardTest")
+	ctx := context.Background()
+	name := newTestKey(t, comp, "leaderboard")
+	now := time.Date(2026, 10, 18, 1, 0, 0, 0, time.UTC)
+
+	best := comp.NewLeaderboard(name + ":best")
+	best.now = func() time.Time { return now }
+	latest := comp.NewLeaderboard(name+":latest", WithLeaderboardMode(LeaderboardLatest))
+	incr := comp.NewLeaderboard(name+":incr", WithLeaderboardMode(LeaderboardIncrement))
+	t.Cleanup(func() { _, _ = comp.Del(ctx, best.Key(), latest.Key(), incr.Key()) })
+
+	// 分数相同时先达到该分数的成员排名靠前
+	for _, s := range []struct {
+		member string
+		score  int64
+		want   int64
+	}{{"a", 100, 100}, {"a", 50, 100}, {"b", 100, 100}, {"c", 120, 120}, {"d", -5, -5}} {
+		v, err := best.Submit(ctx, s.member, s.score)
+		assert.NoError(t, err)
+		assert.Equal(t, s.want, v)
+		now = now.Add(time.Minute)
+	}
+	top, err := best.Top(ctx, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []LeaderboardEntry{
+		{Member: "c", Score: 120, Rank: 1},
+		{Member: "a", Score: 100, Rank: 2},
+		{Member: "b", Score: 100, Rank: 3},
+		{Member: "d", Score: -5, Rank: 4},
+	}, top)
+	around, err := best.Around(ctx, "b", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "d"}, []string{around[0].Member, around[1].Member, around[2].Member})
+	entry, err := best.Get(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, entry)
+	n, err := best.Remove(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	entry, err = best.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, &LeaderboardEntry{Member: "b", Score: 100, Rank: 2}, entry)
+	n, err = best.Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	v, err := latest.Submit(ctx, "a", 100)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), v)
+	v, err = latest.Submit(ctx, "a", 30)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(30), v)
+
+	// 上限附近的分数精确保存，累加之后超过上限返回错误并保留原来的分数
+	v, err = incr.Submit(ctx, "a", incr.MaxScore()-1)
+	assert.NoError(t, err)
+	assert.Equal(t, incr.MaxScore()-1, v)
+	v, err = incr.Submit(ctx, "a", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, incr.MaxScore(), v)
+	_, err = incr.Submit(ctx, "a", 1)
+	assert.Error(t, err)
+	entry, err = incr.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, incr.MaxScore(), entry.Score)
+	v, err = incr.Submit(ctx, "b", -incr.MaxScore())
+	assert.NoError(t, err)
+	assert.Equal(t, -incr.MaxScore(), v)
+	entry, err = incr.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, -incr.MaxScore(), entry.Score)
+}
+
+func TestLeaderboardRollup(t *testing.T) {
+	comp := newTestRedis(t, "redis.leaderboardTest")
+	ctx := context.Background()
+	name := newTestKey(t, comp, "leaderboard")
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+
+	newBoards := func(mode LeaderboardMode, opts ...LeaderboardOption) (*Leaderboard, *Leaderboard) {
+		name := name + ":" + string(mode)
+		opts = append(opts, WithLeaderboardMode(mode), WithLeaderboardLocation(time.UTC))
+		daily := comp.NewLeaderboard(name, append(opts, WithLeaderboardPeriod(LeaderboardDaily))...)
+		weekly := comp.NewLeaderboard(name, append(opts, WithLeaderboardPeriod(LeaderboardWeekly))...)
+		t.Cleanup(func() {
+			keys := []string{weekly.At(monday).Key()}
+			for i := 0; i < 7; i++ {
+				keys = append(keys, daily.At(monday.AddDate(0, 0, i)).Key())
+			}
+			_, _ = comp.Del(ctx, keys...)
+		})
+		return daily, weekly
+	}
+	submit := func(l *Leaderboard, at time.Time, member string, score int64) {
+		_, err := l.At(at).Submit(ctx, member, score)
+		assert.NoError(t, err)
+	}
+	members := func(entries []LeaderboardEntry) []LeaderboardEntry {
+		for i := range entries {
+			entries[i].Rank = 0
+		}
+		return entries
+	}
+
+	// increment 累加分数，总分相同时最后一次提交更早的成员排名靠前
+	daily, weekly := newBoards(LeaderboardIncrement)
+	submit(daily, monday.Add(10*time.Hour), "a", 5)
+	submit(daily, monday.Add(11*time.Hour), "b", 3)
+	submit(daily, monday.Add(33*time.Hour), "b", 2)
+	submit(daily, monday.Add(50*time.Hour), "c", 7)
+	n, err := daily.Rollup(ctx, weekly, monday, monday.AddDate(0, 0, 7))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	top, err := weekly.At(monday).Top(ctx, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []LeaderboardEntry{{Member: "c", Score: 7}, {Member: "a", Score: 5}, {Member: "b", Score: 5}}, members(top))
+
+	// 超过目标排行榜的上限时返回错误，目标排行榜保持不变
+	submit(daily, monday, "d", weekly.MaxScore()+1)
+	_, err = daily.Rollup(ctx, weekly, monday, monday.AddDate(0, 0, 7))
+	assert.Error(t, err)
+	n, err = weekly.At(monday).Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	// best 取最高分，最高分相同时先达到的成员排名靠前
+	daily, weekly = newBoards(LeaderboardBest)
+	submit(daily, monday.Add(10*time.Hour), "a", 10)
+	submit(daily, monday.Add(9*time.Hour), "b", 8)
+	submit(daily, monday.Add(30*time.Hour), "b", 10)
+	submit(daily, monday.Add(31*time.Hour), "a", 10)
+	_, err = daily.Rollup(ctx, weekly, monday, monday.AddDate(0, 0, 7))
+	assert.NoError(t, err)
+	top, err = weekly.At(monday).Top(ctx, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []LeaderboardEntry{{Member: "a", Score: 10}, {Member: "b", Score: 10}}, members(top))
+
+	// 关闭 tie-break 时使用 ZUNIONSTORE
+	daily, weekly = newBoards(LeaderboardLatest, WithLeaderboardTieBreak(false))
+	submit(daily, monday, "a", 3)
+	submit(daily, monday.AddDate(0, 0, 1), "a", 9)
+	_, err = daily.Rollup(ctx, weekly, monday, monday.AddDate(0, 0, 7))
+	assert.NoError(t, err)
+	entry, err := weekly.At(monday).Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, &LeaderboardEntry{Member: "a", Score: 9, Rank: 1}, entry)
+}
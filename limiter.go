@@ -0,0 +1,261 @@
+package eredis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 分布式限流算法
+const (
+	// LimitGCRA 通用信元速率算法，请求均匀放行，允许 Burst 大小的突发
+	LimitGCRA = "gcra"
+	// LimitSlidingWindow 滑动窗口日志，任意 Period 时间内最多放行 Rate 个请求，内存开销与 Rate 成正比
+	LimitSlidingWindow = "sliding_window"
+	// LimitFixedWindow 固定窗口计数，窗口从第一个请求开始计时，开销最小但窗口边界处可能放行 2 倍请求
+	LimitFixedWindow = "fixed_window"
+)
+
+const limiterPrefix = "eredis:limiter:"
+
+// 以下脚本统一返回 {allowed, remaining, retry_after_ms, reset_after_ms}，retry_after_ms 为 -1 表示无需重试或永远无法放行
+var (
+	luaGCRA = redis.NewScript(`
+redis.replicate_commands()
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+
+local emission_interval = period / rate
+local increment = emission_interval * cost
+local burst_offset = emission_interval * burst
+
+-- 以 2017-01-01 为起点，减小浮点数的位数，避免 tostring 时丢失精度
+local t = redis.call("TIME")
+local now = (tonumber(t[1]) - 1483228800) * 1000 + tonumber(t[2]) / 1000
+
+local tat = redis.call("GET", KEYS[1])
+if not tat then
+  tat = now
+else
+  tat = math.max(tonumber(tat), now)
+end
+
+local new_tat = tat + increment
+local diff = now - (new_tat - burst_offset)
+if diff < 0 then
+  if increment > burst_offset then
+    return {0, 0, -1, math.ceil(tat - now)}
+  end
+  return {0, 0, math.ceil(-diff), math.ceil(tat - now)}
+end
+
+local reset_after = new_tat - now
+if reset_after > 0 then
+  redis.call("SET", KEYS[1], tostring(new_tat), "PX", math.ceil(reset_after))
+end
+return {1, math.floor(diff / emission_interval), -1, math.ceil(reset_after)}
+`)
+
+	luaSlidingWindow = redis.NewScript(`
+redis.replicate_commands()
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local token = ARGV[4]
+
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+window = window * 1000
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+if count + cost > limit then
+  local reset_after = 0
+  local newest = redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")
+  if newest[2] then
+    reset_after = tonumber(newest[2]) + window - now
+  end
+  if cost > limit then
+    return {0, limit - count, -1, math.ceil(reset_after / 1000)}
+  end
+  local idx = count + cost - limit - 1
+  local oldest = redis.call("ZRANGE", KEYS[1], idx, idx, "WITHSCORES")
+  local retry_after = tonumber(oldest[2]) + window - now
+  return {0, limit - count, math.ceil(retry_after / 1000), math.ceil(reset_after / 1000)}
+end
+
+for i = 1, cost do
+  redis.call("ZADD", KEYS[1], now, token .. ":" .. i)
+end
+redis.call("PEXPIRE", KEYS[1], math.ceil(window / 1000))
+return {1, limit - count - cost, -1, math.ceil(window / 1000)}
+`)
+
+	luaFixedWindow = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local ttl = redis.call("PTTL", KEYS[1])
+if current + cost > limit then
+  if ttl < 0 then
+    ttl = period
+    redis.call("PEXPIRE", KEYS[1], period)
+  end
+  local retry_after = ttl
+  if cost > limit then
+    retry_after = -1
+  end
+  return {0, math.max(limit - current, 0), retry_after, ttl}
+end
+
+current = redis.call("INCRBY", KEYS[1], cost)
+if ttl < 0 then
+  ttl = period
+  redis.call("PEXPIRE", KEYS[1], period)
+end
+return {1, limit - current, -1, ttl}
+`)
+)
+
+// Limit 限流规则
+type Limit struct {
+	Algorithm string        // Algorithm 限流算法 gcra|sliding_window|fixed_window，默认 gcra
+	Rate      int           // Rate 每个 Period 允许的请求数
+	Period    time.Duration // Period 时间周期
+	Burst     int           // Burst GCRA 算法的突发容量，默认与 Rate 相同
+}
+
+// PerSecond 每秒 rate 个请求的 GCRA 限流规则
+func PerSecond(rate int) Limit {
+	return Limit{Algorithm: LimitGCRA, Rate: rate, Period: time.Second, Burst: rate}
+}
+
+// PerMinute 每分钟 rate 个请求的 GCRA 限流规则
+func PerMinute(rate int) Limit {
+	return Limit{Algorithm: LimitGCRA, Rate: rate, Period: time.Minute, Burst: rate}
+}
+
+// PerHour 每小时 rate 个请求的 GCRA 限流规则
+func PerHour(rate int) Limit {
+	return Limit{Algorithm: LimitGCRA, Rate: rate, Period: time.Hour, Burst: rate}
+}
+
+func (l Limit) algorithm() string {
+	if l.Algorithm == "" {
+		return LimitGCRA
+	}
+	return l.Algorithm
+}
+
+// LimitResult 限流结果
+type LimitResult struct {
+	Limit      Limit         // Limit 使用的限流规则
+	Allowed    int           // Allowed 本次放行的请求数，0 表示被限流
+	Remaining  int           // Remaining 剩余可放行的请求数
+	RetryAfter time.Duration // RetryAfter 被限流时多久后可以重试，-1 表示无需重试或永远无法放行
+	ResetAfter time.Duration // ResetAfter 多久后限流状态完全恢复
+}
+
+// Limiter 基于 redis 的分布式限流器，每种算法都由一个 lua 脚本原子完成。
+// 每个限流 key 只对应一个 redis key，并使用 hash tag 包裹，cluster 模式下可以直接使用。
+type Limiter struct {
+	comp   *Component
+	client redis.Cmdable
+	prefix string
+}
+
+// Limiter 返回分布式限流器
+func (r *Component) Limiter() *Limiter {
+	return &Limiter{comp: r, client: r.client, prefix: limiterPrefix}
+}
+
+// Allow 尝试放行 1 个请求
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit) (*LimitResult, error) {
+	return l.AllowN(ctx, key, limit, 1)
+}
+
+// AllowN 尝试放行 n 个请求，要么全部放行，要么全部拒绝
+func (l *Limiter) AllowN(ctx context.Context, key string, limit Limit, n int) (*LimitResult, error) {
+	if limit.Rate <= 0 || limit.Period <= 0 || n <= 0 {
+		return nil, ErrInvalidParams
+	}
+
+	var (
+		res interface{}
+		err error
+	)
+	keys := []string{l.key(key, limit)}
+	period := strconv.FormatInt(limit.Period.Milliseconds(), 10)
+	switch limit.algorithm() {
+	case LimitGCRA:
+		burst := limit.Burst
+		if burst <= 0 {
+			burst = limit.Rate
+		}
+		res, err = luaGCRA.Run(ctx, l.client, keys, burst, limit.Rate, period, n).Result()
+	case LimitSlidingWindow:
+		token, e := randomToken()
+		if e != nil {
+			return nil, l.comp.wrapErr("evalsha", e)
+		}
+		res, err = luaSlidingWindow.Run(ctx, l.client, keys, limit.Rate, period, n, token).Result()
+	case LimitFixedWindow:
+		res, err = luaFixedWindow.Run(ctx, l.client, keys, limit.Rate, period, n).Result()
+	default:
+		return nil, ErrInvalidParams
+	}
+	if err != nil {
+		return nil, l.comp.wrapErr("evalsha", err)
+	}
+
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 4 {
+		return nil, l.comp.wrapErr("evalsha", fmt.Errorf("eredis limiter unexpected reply %v", res))
+	}
+	ints := make([]int64, 4)
+	for i, v := range values {
+		ints[i], _ = v.(int64)
+	}
+
+	result := &LimitResult{
+		Limit:      limit,
+		Remaining:  int(ints[1]),
+		RetryAfter: time.Duration(ints[2]) * time.Millisecond,
+		ResetAfter: time.Duration(ints[3]) * time.Millisecond,
+	}
+	if ints[0] == 1 {
+		result.Allowed = n
+	}
+	if ints[2] < 0 {
+		result.RetryAfter = -1
+	}
+	return result, nil
+}
+
+// Reset 清除 key 的限流状态
+func (l *Limiter) Reset(ctx context.Context, key string, limit Limit) error {
+	return l.comp.cmdErr(l.client.Del(ctx, l.key(key, limit)))
+}
+
+// key 使用 hash tag 包裹业务 key，保证同一个业务 key 派生出的 redis key 位于同一个 slot
+func (l *Limiter) key(key string, limit Limit) string {
+	return l.prefix + limit.algorithm() + ":{" + key + "}"
+}
+
+// randomToken 生成随机 token，用于区分滑动窗口中同一微秒内的请求
+func randomToken() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,73 @@
+package eredis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterInvalidParams(t *testing.T) {
+	l := (&Component{config: &config{Addr: "127.0.0.1:6379"}}).Limiter()
+	ctx := context.Background()
+
+	_, err := l.Allow(ctx, "a", Limit{Rate: 0, Period: time.Second})
+	assert.ErrorIs(t, err, ErrInvalidParams)
+	_, err = l.AllowN(ctx, "a", PerSecond(1), 0)
+	assert.ErrorIs(t, err, ErrInvalidParams)
+	_, err = l.Allow(ctx, "a", Limit{Algorithm: "leaky_bucket", Rate: 1, Period: time.Second})
+	assert.ErrorIs(t, err, ErrInvalidParams)
+}
+
+func TestLimiterWrapErr(t *testing.T) {
+	comp := buildUnreachable(t, "redis.limiter", "")
+	_, err := comp.Limiter().Allow(context.Background(), "a", PerSecond(1))
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "evalsha", e.Cmd)
+}
+
+func TestLimiter(t *testing.T) {
+	comp := newTestRedis(t, "redis.limiterTest")
+	l := comp.Limiter()
+	ctx := context.Background()
+
+	for _, algorithm := range []string{LimitGCRA, LimitSlidingWindow, LimitFixedWindow} {
+		t.Run(algorithm, func(t *testing.T) {
+			key, err := randomToken()
+			assert.NoError(t, err)
+			limit := Limit{Algorithm: algorithm, Rate: 2, Period: time.Second}
+			defer l.Reset(ctx, key, limit)
+
+			for i := 0; i < 2; i++ {
+				res, err := l.Allow(ctx, key, limit)
+				assert.NoError(t, err)
+				assert.Equal(t, 1, res.Allowed)
+				assert.Equal(t, 1-i, res.Remaining)
+				assert.Equal(t, time.Duration(-1), res.RetryAfter)
+			}
+
+			// 超出限制后拒绝，并返回重试时间
+			res, err := l.Allow(ctx, key, limit)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, res.Allowed)
+			assert.Greater(t, res.RetryAfter, time.Duration(0))
+			assert.LessOrEqual(t, res.RetryAfter, time.Second)
+
+			// cost 超过限制时永远无法放行
+			res, err = l.AllowN(ctx, key, limit, 3)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, res.Allowed)
+			assert.Equal(t, time.Duration(-1), res.RetryAfter)
+
+			// 重置之后恢复
+			assert.NoError(t, l.Reset(ctx, key, limit))
+			res, err = l.AllowN(ctx, key, limit, 2)
+			assert.NoError(t, err)
+			assert.Equal(t, 2, res.Allowed)
+			assert.Equal(t, 0, res.Remaining)
+		})
+	}
+}
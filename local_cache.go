@@ -0,0 +1,203 @@
+package eredis
+
+import (
+	"container/heap"
+	"container/list"
+	"sync"
+	"time"
+)
+
+// 本地缓存淘汰策略
+const (
+	// LocalCacheLRU 淘汰最久未访问的 key
+	LocalCacheLRU = "lru"
+	// LocalCacheLFU 淘汰访问次数最少的 key，次数相同时淘汰最久未访问的 key
+	LocalCacheLFU = "lfu"
+)
+
+type localEntry struct {
+	key      string
+	value    string
+	expireAt time.Time
+	freq     int64
+	tick     int64
+	index    int           // index LFU 堆中的位置
+	elem     *list.Element // elem LRU 链表中的位置
+}
+
+// lfuHeap 按访问次数、访问时间排序的小顶堆
+type lfuHeap []*localEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *lfuHeap) Push(x interface{}) {
+	e := x.(*localEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+// localCache 进程内缓存，按条数和过期时间限制大小
+type localCache struct {
+	policy string
+	size   int
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	items map[string]*localEntry
+	lru   *list.List
+	lfu   lfuHeap
+	tick  int64
+	epoch uint64 // epoch 每次失效都会递增，用于丢弃失效前发起的回填
+}
+
+func newLocalCache(policy string, size int, ttl time.Duration) *localCache {
+	return &localCache{
+		policy: policy,
+		size:   size,
+		ttl:    ttl,
+		now:    time.Now,
+		items:  make(map[string]*localEntry),
+		lru:    list.New(),
+	}
+}
+
+// get 读取缓存，过期的 key 会被删除
+func (l *localCache) get(key string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.items[key]
+	if !ok {
+		return "", false
+	}
+	if !e.expireAt.IsZero() && !l.now().Before(e.expireAt) {
+		l.remove(e)
+		return "", false
+	}
+	l.touch(e)
+	return e.value, true
+}
+
+// currentEpoch 返回当前的失效版本，回填前获取，回填时传给 set
+func (l *localCache) currentEpoch() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.epoch
+}
+
+// set 写入缓存，epoch 与当前版本不一致时说明读取期间发生过失效，放弃写入，返回是否有 key 被淘汰
+func (l *localCache) set(key, value string, epoch uint64) (evicted bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if epoch != l.epoch {
+		return false
+	}
+	var expireAt time.Time
+	if l.ttl > 0 {
+		expireAt = l.now().Add(l.ttl)
+	}
+	if e, ok := l.items[key]; ok {
+		e.value, e.expireAt = value, expireAt
+		l.touch(e)
+		return false
+	}
+
+	if l.size > 0 && len(l.items) >= l.size {
+		l.evict()
+		evicted = true
+	}
+	e := &localEntry{key: key, value: value, expireAt: expireAt}
+	l.items[key] = e
+	if l.policy == LocalCacheLFU {
+		l.tick++
+		e.freq, e.tick = 1, l.tick
+		heap.Push(&l.lfu, e)
+	} else {
+		e.elem = l.lru.PushFront(e)
+	}
+	return evicted
+}
+
+// del 删除 key
+func (l *localCache) del(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.epoch++
+	for _, key := range keys {
+		if e, ok := l.items[key]; ok {
+			l.remove(e)
+		}
+	}
+}
+
+// purge 清空缓存
+func (l *localCache) purge() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.epoch++
+	l.items = make(map[string]*localEntry)
+	l.lru.Init()
+	l.lfu = nil
+}
+
+func (l *localCache) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.items)
+}
+
+// touch 记录一次访问，调用方需持有锁
+func (l *localCache) touch(e *localEntry) {
+	if l.policy == LocalCacheLFU {
+		l.tick++
+		e.freq++
+		e.tick = l.tick
+		heap.Fix(&l.lfu, e.index)
+		return
+	}
+	l.lru.MoveToFront(e.elem)
+}
+
+// evict 按淘汰策略淘汰一个 key，调用方需持有锁
+func (l *localCache) evict() {
+	if l.policy == LocalCacheLFU {
+		if len(l.lfu) > 0 {
+			l.remove(l.lfu[0])
+		}
+		return
+	}
+	if back := l.lru.Back(); back != nil {
+		l.remove(back.Value.(*localEntry))
+	}
+}
+
+// remove 删除一个 key，调用方需持有锁
+func (l *localCache) remove(e *localEntry) {
+	delete(l.items, e.key)
+	if l.policy == LocalCacheLFU {
+		heap.Remove(&l.lfu, e.index)
+		return
+	}
+	l.lru.Remove(e.elem)
+}
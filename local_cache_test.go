@@ -0,0 +1,71 @@
+package eredis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalCacheLRU(t *testing.T) {
+	l := newLocalCache(LocalCacheLRU, 2, 0)
+	l.set("a", "1", l.currentEpoch())
+	l.set("b", "2", l.currentEpoch())
+	_, ok := l.get("a")
+	assert.True(t, ok)
+
+	// b 最久未访问，被淘汰
+	assert.True(t, l.set("c", "3", l.currentEpoch()))
+	_, ok = l.get("b")
+	assert.False(t, ok)
+	v, _ := l.get("a")
+	assert.Equal(t, "1", v)
+	assert.Equal(t, 2, l.len())
+}
+
+func TestLocalCacheLFU(t *testing.T) {
+	l := newLocalCache(LocalCacheLFU, 2, 0)
+	l.set("a", "1", l.currentEpoch())
+	l.set("b", "2", l.currentEpoch())
+	l.get("a")
+	l.get("a")
+	l.get("b")
+
+	// b 访问次数更少，被淘汰
+	l.set("c", "3", l.currentEpoch())
+	_, ok := l.get("b")
+	assert.False(t, ok)
+	_, ok = l.get("a")
+	assert.True(t, ok)
+
+	l.del("a", "c")
+	assert.Equal(t, 0, l.len())
+}
+
+func TestLocalCacheTTL(t *testing.T) {
+	now := time.Now()
+	l := newLocalCache(LocalCacheLRU, 10, time.Second)
+	l.now = func() time.Time { return now }
+	l.set("a", "1", l.currentEpoch())
+	_, ok := l.get("a")
+	assert.True(t, ok)
+
+	now = now.Add(time.Second)
+	_, ok = l.get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, l.len())
+}
+
+func TestLocalCacheEpoch(t *testing.T) {
+	l := newLocalCache(LocalCacheLRU, 10, 0)
+	epoch := l.currentEpoch()
+	// 读取 redis 期间收到失效消息，回填的旧值被丢弃
+	l.del("a")
+	l.set("a", "stale", epoch)
+	_, ok := l.get("a")
+	assert.False(t, ok)
+
+	l.set("a", "1", l.currentEpoch())
+	l.purge()
+	assert.Equal(t, 0, l.len())
+}
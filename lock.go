@@ -119,7 +119,7 @@ func (l *Lock) Metadata() string {
 // TTL returns the remaining time-to-live. Returns 0 if the Lock has expired.
 func (l *Lock) TTL(ctx context.Context) (time.Duration, error) {
 	res, err := luaPTTL.Run(ctx, l.client.client, []string{l.key}, l.value).Result()
-	if err == redis.Nil {
+	if IsNil(err) {
 		return 0, nil
 	} else if err != nil {
 		return 0, err
@@ -148,7 +148,7 @@ func (l *Lock) Refresh(ctx context.Context, ttl time.Duration, opts ...LockOptio
 // May return ErrLockNotHeld.
 func (l *Lock) Release(ctx context.Context) error {
 	res, err := luaRelease.Run(ctx, l.client.client, []string{l.key}, l.value).Result()
-	if err == redis.Nil {
+	if IsNil(err) {
 		return ErrLockNotHeld
 	} else if err != nil {
 		return err
@@ -76,3 +76,17 @@ func WithDB(db int) Option {
 		c.config.DB = db
 	}
 }
+
+// WithCodec set codec used by GetValue, SetValue and other typed helpers
+func WithCodec(codec Codec) Option {
+	return func(c *Container) {
+		c.config.codec = codec
+	}
+}
+
+// WithEncryptionKeyLoader set key loader for value encryption, it takes precedence over Encryption.KeyFile
+func WithEncryptionKeyLoader(loader KeyLoader) Option {
+	return func(c *Container) {
+		c.config.keyLoader = loader
+	}
+}
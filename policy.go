@@ -0,0 +1,304 @@
+package eredis
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/redis/go-redis/v9"
+)
+
+// 写入策略检查模式
+const (
+	// PolicyModeWarn 只记录日志和监控，命令正常执行
+	PolicyModeWarn = "warn"
+	// PolicyModeReject 拒绝违反策略的写入，返回 ErrPolicyViolation
+	PolicyModeReject = "reject"
+)
+
+// 违反的策略
+const (
+	policyRuleValueSize = "value_size"
+	policyRuleNoTTL     = "no_ttl"
+)
+
+var policyViolationCounter = emetric.CounterVecOpts{
+	Namespace: emetric.DefaultNamespace,
+	Name:      "client_redis_policy_violation_total",
+	Help:      "redis writes violating the value size or ttl policy",
+	Labels:    []string{"name", "cmd", "rule", "mode"},
+}.Build()
+
+// PolicyConfig 写入策略配置，检查过大的 value 和没有设置过期时间的 key
+type PolicyConfig struct {
+	Mode               string        // Mode 检查模式 warn|reject，默认 warn
+	MaxValueSize       int           // MaxValueSize 单个 value 的最大字节数，0 表示不检查，默认 1MB
+	TTLPrefixes        []string      // TTLPrefixes 必须设置过期时间的 key 前缀，为空表示不检查
+	TTLCheckDelay      time.Duration // TTLCheckDelay HSET、LPUSH 等命令写入之后，延迟多久检查 key 是否设置了过期时间，默认 1s
+	TTLCheckSampleRate float64       // TTLCheckSampleRate 延迟检查的采样率，取值 (0, 1]，默认 0.1
+}
+
+// DefaultPolicyConfig 默认写入策略配置
+func DefaultPolicyConfig() PolicyConfig {
+	return PolicyConfig{
+		Mode:               PolicyModeWarn,
+		MaxValueSize:       1 << 20,
+		TTLCheckDelay:      time.Second,
+		TTLCheckSampleRate: 0.1,
+	}
+}
+
+// valueWriteCommands 检查 value 大小的写命令，value 从第 2 个参数开始（MSET 从第 1 个）
+var valueWriteCommands = map[string]struct{}{
+	"set": {}, "setnx": {}, "setex": {}, "psetex": {}, "getset": {}, "mset": {}, "msetnx": {}, "append": {}, "setrange": {},
+	"hset": {}, "hsetnx": {}, "hmset": {}, "lpush": {}, "rpush": {}, "lpushx": {}, "rpushx": {}, "linsert": {}, "lset": {},
+	"sadd": {}, "zadd": {}, "xadd": {}, "geoadd": {}, "pfadd": {},
+}
+
+// collectionWriteCommands 可能创建 key 但不能同时设置过期时间的命令，写入之后延迟检查 TTL
+var collectionWriteCommands = map[string]struct{}{
+	"hset": {}, "hsetnx": {}, "hmset": {}, "lpush": {}, "rpush": {}, "sadd": {}, "zadd": {}, "xadd": {}, "geoadd": {}, "pfadd": {},
+}
+
+// expireCommands 设置过期时间的命令
+var expireCommands = map[string]struct{}{
+	"expire": {}, "pexpire": {}, "expireat": {}, "pexpireat": {},
+}
+
+// policyViolation 一次违反策略的写入
+type policyViolation struct {
+	rule string
+	key  string
+	size int
+}
+
+// policy 写入策略检查
+type policy struct {
+	name   string
+	config PolicyConfig
+	mode   string
+	logger *elog.Component
+	client redis.Cmdable
+
+	// pending 等待延迟检查 TTL 的 key，避免同一个 key 重复检查
+	pending sync.Map
+	// afterFunc 延迟执行，单元测试中替换
+	afterFunc func(time.Duration, func())
+}
+
+func newPolicy(compName string, config PolicyConfig, logger *elog.Component) *policy {
+	mode := PolicyModeWarn
+	if config.Mode == PolicyModeReject {
+		mode = PolicyModeReject
+	}
+	return &policy{
+		name:   compName,
+		config: config,
+		mode:   mode,
+		logger: logger,
+		afterFunc: func(d time.Duration, f func()) {
+			time.AfterFunc(d, f)
+		},
+	}
+}
+
+// check 检查一组命令，同一个 pipeline 中对 key 执行了 EXPIRE 时不认为缺少过期时间。
+// 返回立即可以判断的违规，collection 类写入需要延迟检查的 key 放在 deferred 中。
+func (p *policy) check(cmds ...redis.Cmder) (violations []policyViolation, deferred []string) {
+	var expired map[string]struct{}
+	if len(p.config.TTLPrefixes) > 0 {
+		for _, cmd := range cmds {
+			args := cmd.Args()
+			if _, ok := expireCommands[strings.ToLower(cmd.Name())]; ok && len(args) > 1 {
+				if expired == nil {
+					expired = make(map[string]struct{})
+				}
+				expired[fmt.Sprint(args[1])] = struct{}{}
+			}
+		}
+	}
+
+	for _, cmd := range cmds {
+		name := strings.ToLower(cmd.Name())
+		args := cmd.Args()
+		if _, ok := valueWriteCommands[name]; !ok || len(args) < 2 {
+			continue
+		}
+		if p.config.MaxValueSize > 0 {
+			if size := maxValueSize(name, args); size > p.config.MaxValueSize {
+				violations = append(violations, policyViolation{rule: policyRuleValueSize, key: fmt.Sprint(args[1]), size: size})
+			}
+		}
+		for _, key := range noTTLKeys(name, args) {
+			if _, ok := expired[key]; ok || !p.requireTTL(key) {
+				continue
+			}
+			if _, ok := collectionWriteCommands[name]; ok {
+				deferred = append(deferred, key)
+				continue
+			}
+			violations = append(violations, policyViolation{rule: policyRuleNoTTL, key: key})
+		}
+	}
+	return violations, deferred
+}
+
+// requireTTL key 是否必须设置过期时间
+func (p *policy) requireTTL(key string) bool {
+	for _, prefix := range p.config.TTLPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxValueSize 返回写命令中最大的 value 字节数
+func maxValueSize(name string, args []interface{}) int {
+	start := 2
+	if name == "mset" || name == "msetnx" {
+		start = 1
+	}
+	max := 0
+	for _, arg := range args[start:] {
+		var size int
+		switch v := arg.(type) {
+		case string:
+			size = len(v)
+		case []byte:
+			size = len(v)
+		default:
+			continue
+		}
+		if size > max {
+			max = size
+		}
+	}
+	return max
+}
+
+// noTTLKeys 返回写命令中没有设置过期时间的 key
+func noTTLKeys(name string, args []interface{}) []string {
+	switch name {
+	case "set":
+		for _, arg := range args[3:] {
+			switch strings.ToLower(fmt.Sprint(arg)) {
+			case "ex", "px", "exat", "pxat", "keepttl":
+				return nil
+			}
+		}
+		return []string{fmt.Sprint(args[1])}
+	case "setnx", "getset":
+		return []string{fmt.Sprint(args[1])}
+	case "mset", "msetnx":
+		keys := make([]string, 0, len(args)/2)
+		for i := 1; i < len(args); i += 2 {
+			keys = append(keys, fmt.Sprint(args[i]))
+		}
+		return keys
+	}
+	if _, ok := collectionWriteCommands[name]; ok {
+		return []string{fmt.Sprint(args[1])}
+	}
+	return nil
+}
+
+// report 记录违规的日志和监控
+func (p *policy) report(cmd string, v policyViolation) {
+	policyViolationCounter.Inc(p.name, cmd, v.rule, p.mode)
+	fields := []elog.Field{
+		elog.FieldName(p.name),
+		elog.FieldMethod(cmd),
+		elog.FieldKey(v.key),
+		elog.String("rule", v.rule),
+		elog.String("mode", p.mode),
+	}
+	if v.rule == policyRuleValueSize {
+		fields = append(fields, elog.Int("size", v.size), elog.Int("maxValueSize", p.config.MaxValueSize))
+	}
+	if caller := fileWithLineNum(); caller != "" {
+		fields = append(fields, elog.String("caller", caller))
+	}
+	p.logger.Warn("redis policy violation", fields...)
+}
+
+// checkTTLLater 延迟检查 key 是否设置了过期时间，只能记录违规，不能拒绝写入
+func (p *policy) checkTTLLater(cmd string, key string) {
+	if p.client == nil || rand.Float64() >= p.config.TTLCheckSampleRate {
+		return
+	}
+	if _, loaded := p.pending.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	p.afterFunc(p.config.TTLCheckDelay, func() {
+		defer p.pending.Delete(key)
+		ttl, err := p.client.TTL(context.Background(), key).Result()
+		if err != nil {
+			return
+		}
+		// -1 表示 key 存在但没有过期时间
+		if ttl == -1 {
+			p.report(cmd, policyViolation{rule: policyRuleNoTTL, key: key})
+		}
+	})
+}
+
+// process 检查命令，拒绝模式下返回 ErrPolicyViolation
+func (p *policy) process(cmds ...redis.Cmder) error {
+	violations, deferred := p.check(cmds...)
+	if len(violations) == 0 {
+		for _, key := range deferred {
+			p.checkTTLLater(policyCmdName(cmds, key), key)
+		}
+		return nil
+	}
+	for _, v := range violations {
+		p.report(policyCmdName(cmds, v.key), v)
+	}
+	if p.mode == PolicyModeReject {
+		return ErrPolicyViolation
+	}
+	for _, key := range deferred {
+		p.checkTTLLater(policyCmdName(cmds, key), key)
+	}
+	return nil
+}
+
+// policyCmdName 返回写入 key 的命令名称
+func policyCmdName(cmds []redis.Cmder, key string) string {
+	for _, cmd := range cmds {
+		if args := cmd.Args(); len(args) > 1 && fmt.Sprint(args[1]) == key {
+			return strings.ToLower(cmd.Name())
+		}
+	}
+	if len(cmds) == 1 {
+		return strings.ToLower(cmds[0].Name())
+	}
+	return "pipeline"
+}
+
+// policyInterceptor 写入策略拦截器
+func policyInterceptor(p *policy) *interceptor {
+	return newInterceptor(p.name, nil, p.logger).
+		setBeforeProcess(func(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+			if err := p.process(cmd); err != nil {
+				cmd.SetErr(err)
+				return ctx, err
+			}
+			return ctx, nil
+		}).
+		setBeforeProcessPipeline(func(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+			if err := p.process(cmds...); err != nil {
+				for _, cmd := range cmds {
+					cmd.SetErr(err)
+				}
+				return ctx, err
+			}
+			return ctx, nil
+		})
+}
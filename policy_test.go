@@ -0,0 +1,69 @@
+package eredis
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultPolicyConfig()
+	config.MaxValueSize = 8
+	config.TTLPrefixes = []string{"session:"}
+	p := newPolicy("test", config, elog.DefaultLogger)
+
+	cases := []struct {
+		cmds     []redis.Cmder
+		rules    []string
+		deferred []string
+	}{
+		{[]redis.Cmder{redis.NewCmd(ctx, "set", "a", "small")}, nil, nil},
+		{[]redis.Cmder{redis.NewCmd(ctx, "set", "a", strings.Repeat("x", 9))}, []string{policyRuleValueSize}, nil},
+		{[]redis.Cmder{redis.NewCmd(ctx, "mset", "a", "1", "b", strings.Repeat("x", 9))}, []string{policyRuleValueSize}, nil},
+		{[]redis.Cmder{redis.NewCmd(ctx, "get", strings.Repeat("x", 9))}, nil, nil},
+		{[]redis.Cmder{redis.NewCmd(ctx, "set", "session:1", "v")}, []string{policyRuleNoTTL}, nil},
+		{[]redis.Cmder{redis.NewCmd(ctx, "set", "session:1", "v", "ex", 10)}, nil, nil},
+		{[]redis.Cmder{redis.NewCmd(ctx, "set", "session:1", "v", "KEEPTTL")}, nil, nil},
+		{[]redis.Cmder{redis.NewCmd(ctx, "setex", "session:1", 10, "v")}, nil, nil},
+		{[]redis.Cmder{redis.NewCmd(ctx, "hset", "session:1", "f", "v")}, nil, []string{"session:1"}},
+		// 同一个 pipeline 中设置了过期时间
+		{[]redis.Cmder{redis.NewCmd(ctx, "hset", "session:1", "f", "v"), redis.NewCmd(ctx, "expire", "session:1", 10)}, nil, nil},
+		{[]redis.Cmder{redis.NewCmd(ctx, "hset", "cache:1", "f", "v")}, nil, nil},
+	}
+	for _, c := range cases {
+		violations, deferred := p.check(c.cmds...)
+		var rules []string
+		for _, v := range violations {
+			rules = append(rules, v.rule)
+		}
+		assert.Equal(t, c.rules, rules, c.cmds[0].Args())
+		assert.Equal(t, c.deferred, deferred, c.cmds[0].Args())
+	}
+
+	// reject 模式下拒绝写入，warn 模式下放行
+	assert.NoError(t, p.process(redis.NewCmd(ctx, "set", "session:1", "v")))
+	config.Mode = PolicyModeReject
+	p = newPolicy("test", config, elog.DefaultLogger)
+	assert.ErrorIs(t, p.process(redis.NewCmd(ctx, "set", "session:1", "v")), ErrPolicyViolation)
+}
+
+func TestPolicyCheckTTLLater(t *testing.T) {
+	config := DefaultPolicyConfig()
+	config.TTLCheckSampleRate = 1
+	p := newPolicy("test", config, elog.DefaultLogger)
+	p.client = redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+
+	var delays []time.Duration
+	p.afterFunc = func(d time.Duration, f func()) { delays = append(delays, d) }
+	// 等待检查期间同一个 key 只检查一次
+	p.checkTTLLater("hset", "session:1")
+	p.checkTTLLater("hset", "session:1")
+	p.checkTTLLater("hset", "session:2")
+	assert.Equal(t, []time.Duration{time.Second, time.Second}, delays)
+}
@@ -0,0 +1,275 @@
+package eredis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// prioritySeqBits score 中序号占用的位数，score = priority<<40 + seq，保证在 float64 中精确表示
+	prioritySeqBits = 40
+	// MaxPriority 优先级的最大值，数值越小越先出队
+	MaxPriority = 1<<(53-prioritySeqBits) - 1
+)
+
+var (
+	// luaPriorityPush 生成递增序号，同一优先级内先进先出
+	luaPriorityPush = redis.NewScript(`
+local seq = redis.call("incr", KEYS[3])
+local score = tonumber(ARGV[2]) * 1099511627776 + seq % 1099511627776
+redis.call("hset", KEYS[2], ARGV[1], ARGV[3])
+redis.call("zadd", KEYS[1], string.format("%.0f", score), ARGV[1])
+return 1`)
+
+	// luaPriorityPop 原子地取出优先级最高的元素，跳过内容已经被删除的元素
+	luaPriorityPop = redis.NewScript(`
+while true do
+  local res = redis.call("zpopmin", KEYS[1])
+  if #res == 0 then return false end
+  local payload = redis.call("hget", KEYS[2], res[1])
+  if payload then
+    redis.call("hdel", KEYS[2], res[1])
+    return {res[1], payload, res[2]}
+  end
+end`)
+
+	// luaPriorityTake BZPOPMIN 之后原子地取出元素内容，内容不存在时返回 false
+	luaPriorityTake = redis.NewScript(`
+local payload = redis.call("hget", KEYS[1], ARGV[1])
+if payload then redis.call("hdel", KEYS[1], ARGV[1]) end
+return payload`)
+
+	// luaPriorityUpdate 修改优先级，保留原来的序号
+	luaPriorityUpdate = redis.NewScript(`
+local score = redis.call("zscore", KEYS[1], ARGV[1])
+if not score then return 0 end
+local seq = tonumber(score) % 1099511627776
+redis.call("zadd", KEYS[1], "XX", string.format("%.0f", tonumber(ARGV[2]) * 1099511627776 + seq), ARGV[1])
+return 1`)
+)
+
+// PriorityItem 优先级队列中的元素
+type PriorityItem struct {
+	ID       string
+	Payload  string
+	Priority int64
+}
+
+// PriorityQueueOption 优先级队列选项
+type PriorityQueueOption func(q *PriorityQueue)
+
+// WithPriorityQueueBlock BPop 每次 BZPOPMIN 的最长阻塞时间，每次阻塞结束时检查 ctx 是否取消，默认 1s
+func WithPriorityQueueBlock(block time.Duration) PriorityQueueOption {
+	return func(q *PriorityQueue) {
+		q.block = block
+	}
+}
+
+// PriorityQueue 基于 ZSET 的优先级队列，由 NewPriorityQueue 创建。
+// score 为 priority<<40 + 序号，priority 越小越先出队，同一优先级内先进先出。
+// 所有 key 使用 {name} 作为 hash tag，cluster 模式下位于同一个 slot：
+//
+//	{name}:queue  ZSET 元素 ID
+//	{name}:items  HASH 元素 ID 到内容
+//	{name}:seq    STRING 递增序号
+type PriorityQueue struct {
+	comp  *Component
+	name  string
+	keys  priorityQueueKeys
+	block time.Duration
+}
+
+type priorityQueueKeys struct {
+	queue, items, seq string
+}
+
+// NewPriorityQueue 创建优先级队列
+func (r *Component) NewPriorityQueue(name string, opts ...PriorityQueueOption) *PriorityQueue {
+	prefix := "{" + name + "}:"
+	q := &PriorityQueue{
+		comp: r,
+		name: name,
+		keys: priorityQueueKeys{
+			queue: prefix + "queue",
+			items: prefix + "items",
+			seq:   prefix + "seq",
+		},
+		block: time.Second,
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if q.block <= 0 {
+		q.block = time.Second
+	}
+	return q
+}
+
+// scorePriority 从 score 中取出优先级
+func scorePriority(score float64) int64 {
+	return int64(score) >> prioritySeqBits
+}
+
+func checkPriority(priority int64) error {
+	if priority < 0 || priority > MaxPriority {
+		return fmt.Errorf("%w: priority %d out of range [0, %d]", ErrInvalidParams, priority, MaxPriority)
+	}
+	return nil
+}
+
+// Push 添加元素，返回元素 ID。priority 取值范围为 [0, MaxPriority]，越小越先出队
+func (q *PriorityQueue) Push(ctx context.Context, payload string, priority int64) (string, error) {
+	if err := checkPriority(priority); err != nil {
+		return "", err
+	}
+	id, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	keys := []string{q.keys.queue, q.keys.items, q.keys.seq}
+	if err := luaPriorityPush.Run(ctx, q.comp.client, keys, id, priority, payload).Err(); err != nil {
+		return "", q.comp.wrapErr("evalsha", err)
+	}
+	return id, nil
+}
+
+// Pop 取出优先级最高的元素，队列为空时返回 nil
+func (q *PriorityQueue) Pop(ctx context.Context) (*PriorityItem, error) {
+	res, err := luaPriorityPop.Run(ctx, q.comp.client, []string{q.keys.queue, q.keys.items}).Slice()
+	if IsNil(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, q.comp.wrapErr("evalsha", err)
+	}
+	if len(res) != 3 {
+		return nil, fmt.Errorf("eredis: unexpected pop reply %v", res)
+	}
+	id, _ := res[0].(string)
+	payload, _ := res[1].(string)
+	score, _ := res[2].(string)
+	f, err := strconv.ParseFloat(score, 64)
+	if err != nil {
+		return nil, fmt.Errorf("eredis: invalid priority score %q: %w", score, err)
+	}
+	return &PriorityItem{ID: id, Payload: payload, Priority: scorePriority(f)}, nil
+}
+
+// BPop 阻塞等待优先级最高的元素，直到取到元素或者 ctx 结束，跳过内容已经被 Remove 删除的元素。
+// BZPOPMIN 之后通过 Lua 脚本取出内容，脚本执行失败时把元素放回队列
+func (q *PriorityQueue) BPop(ctx context.Context) (*PriorityItem, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block := q.block
+		if deadline, ok := ctx.Deadline(); ok {
+			if remain := time.Until(deadline); remain < block {
+				block = remain
+			}
+			if block < time.Millisecond {
+				block = time.Millisecond
+			}
+		}
+		res, err := q.comp.client.BZPopMin(ctx, block, q.keys.queue).Result()
+		if IsNil(err) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, q.comp.wrapErr("bzpopmin", err)
+		}
+		id, _ := res.Member.(string)
+		payload, err := luaPriorityTake.Run(ctx, q.comp.client, []string{q.keys.items}, id).Text()
+		if IsNil(err) {
+			// 元素已经被 Remove 删除，继续等待下一个
+			continue
+		}
+		if err != nil {
+			q.requeue(res)
+			return nil, q.comp.wrapErr("evalsha", err)
+		}
+		return &PriorityItem{ID: id, Payload: payload, Priority: scorePriority(res.Score)}, nil
+	}
+}
+
+// requeue 把 BZPOPMIN 取出的元素按原来的 score 放回队列，不受调用方 ctx 取消的影响
+func (q *PriorityQueue) requeue(z *redis.ZWithKey) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.comp.client.ZAddNX(ctx, q.keys.queue, z.Z).Err(); err != nil {
+		q.comp.logger.Error("requeue priority item fail", elog.FieldName(q.comp.name), elog.String("id", fmt.Sprint(z.Member)), elog.FieldErr(err))
+	}
+}
+
+// Peek 查看优先级最高的 n 个元素，不出队
+func (q *PriorityQueue) Peek(ctx context.Context, n int64) ([]PriorityItem, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	zs, err := q.comp.client.ZRangeWithScores(ctx, q.keys.queue, 0, n-1).Result()
+	if err != nil {
+		return nil, q.comp.wrapErr("zrange", err)
+	}
+	if len(zs) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(zs))
+	for _, z := range zs {
+		id, _ := z.Member.(string)
+		ids = append(ids, id)
+	}
+	payloads, err := q.comp.client.HMGet(ctx, q.keys.items, ids...).Result()
+	if err != nil {
+		return nil, q.comp.wrapErr("hmget", err)
+	}
+	items := make([]PriorityItem, 0, len(zs))
+	for i, z := range zs {
+		payload, ok := payloads[i].(string)
+		if !ok {
+			// 元素在两次查询之间被取出
+			continue
+		}
+		items = append(items, PriorityItem{ID: ids[i], Payload: payload, Priority: scorePriority(z.Score)})
+	}
+	return items, nil
+}
+
+// Remove 删除元素，元素不存在时返回 false
+func (q *PriorityQueue) Remove(ctx context.Context, id string) (bool, error) {
+	var rem *redis.IntCmd
+	_, err := q.comp.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		rem = pipe.ZRem(ctx, q.keys.queue, id)
+		pipe.HDel(ctx, q.keys.items, id)
+		return nil
+	})
+	if err != nil {
+		return false, q.comp.wrapErr("zrem", err)
+	}
+	return rem.Val() > 0, nil
+}
+
+// SetPriority 修改元素的优先级，同一优先级内仍然按照入队顺序，元素不存在时返回 false
+func (q *PriorityQueue) SetPriority(ctx context.Context, id string, priority int64) (bool, error) {
+	if err := checkPriority(priority); err != nil {
+		return false, err
+	}
+	n, err := luaPriorityUpdate.Run(ctx, q.comp.client, []string{q.keys.queue}, id, priority).Int64()
+	if err != nil {
+		return false, q.comp.wrapErr("evalsha", err)
+	}
+	return n > 0, nil
+}
+
+// Len 队列中的元素数量
+func (q *PriorityQueue) Len(ctx context.Context) (int64, error) {
+	n, err := q.comp.client.ZCard(ctx, q.keys.queue).Result()
+	return n, q.comp.wrapErr("zcard", err)
+}
@@ -0,0 +1,167 @@
+package eredis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriorityScore(t *testing.T) {
+	r := &Component{name: "redis.test", logger: elog.DefaultLogger}
+	q := r.NewPriorityQueue("tasks", WithPriorityQueueBlock(0))
+	assert.Equal(t, time.Second, q.block)
+	assert.Equal(t, "{tasks}:queue", q.keys.queue)
+
+	// score 与 Lua 脚本中的计算方式一致，整数在 float64 中精确表示
+	assert.Equal(t, int64(8191), int64(MaxPriority))
+	score := float64(int64(MaxPriority)<<prioritySeqBits + 1<<prioritySeqBits - 1)
+	assert.Equal(t, int64(MaxPriority), scorePriority(score))
+	assert.Equal(t, int64(3), scorePriority(float64(3<<prioritySeqBits+42)))
+	assert.Equal(t, int64(0), scorePriority(1))
+
+	assert.NoError(t, checkPriority(0))
+	assert.True(t, errors.Is(checkPriority(-1), ErrInvalidParams))
+	assert.True(t, errors.Is(checkPriority(MaxPriority+1), ErrInvalidParams))
+}
+
+// newTestPriorityQueue 创建使用唯一名称的优先级队列，测试结束后删除所有 key
+func newTestPriorityQueue(t *testing.T, comp *Component, opts ...PriorityQueueOption) *PriorityQueue {
+	name := newTestKey(t, comp, "priority")
+	q := comp.NewPriorityQueue(name, opts...)
+	t.Cleanup(func() {
+		_, _ = comp.Del(context.Background(), q.keys.queue, q.keys.items, q.keys.seq)
+	})
+	return q
+}
+
+// skipWithoutBZPopMin 测试服务端不支持 BZPOPMIN 时跳过
+func skipWithoutBZPopMin(t *testing.T, comp *Component, key string) {
+	err := comp.Client().BZPopMin(context.Background(), 10*time.Millisecond, key).Err()
+	if err != nil && strings.Contains(err.Error(), "unknown command") {
+		t.Skip("redis server does not support BZPOPMIN")
+	}
+}
+
+func TestPriorityQueue(t *testing.T) {
+	comp := newTestRedis(t, "redis.priorityTest")
+	ctx := context.Background()
+	q := newTestPriorityQueue(t, comp)
+
+	ids := make(map[string]string)
+	for _, item := range []struct {
+		payload  string
+		priority int64
+	}{{"low", 5}, {"high-1", 1}, {"max", MaxPriority}, {"high-2", 1}, {"removed", 0}} {
+		id, err := q.Push(ctx, item.payload, item.priority)
+		assert.NoError(t, err)
+		ids[item.payload] = id
+	}
+	_, err := q.Push(ctx, "bad", MaxPriority+1)
+	assert.ErrorIs(t, err, ErrInvalidParams)
+
+	ok, err := q.Remove(ctx, ids["removed"])
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = q.Remove(ctx, ids["removed"])
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// 修改优先级保留原来的序号
+	ok, err = q.SetPriority(ctx, ids["high-1"], 2)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = q.SetPriority(ctx, ids["high-1"], 1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = q.SetPriority(ctx, "missing", 1)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	n, err := q.Len(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	peek, err := q.Peek(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []PriorityItem{
+		{ID: ids["high-1"], Payload: "high-1", Priority: 1},
+		{ID: ids["high-2"], Payload: "high-2", Priority: 1},
+	}, peek)
+
+	// 数值小的优先出队，同一优先级内先进先出
+	for _, want := range []string{"high-1", "high-2", "low", "max"} {
+		item, err := q.Pop(ctx)
+		assert.NoError(t, err)
+		if assert.NotNil(t, item) {
+			assert.Equal(t, ids[want], item.ID)
+			assert.Equal(t, want, item.Payload)
+		}
+	}
+	item, err := q.Pop(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+	exists, err := comp.Client().Exists(ctx, q.keys.items).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), exists)
+}
+
+func TestPriorityQueuePopMissingPayload(t *testing.T) {
+	comp := newTestRedis(t, "redis.priorityTest")
+	ctx := context.Background()
+	q := newTestPriorityQueue(t, comp, WithPriorityQueueBlock(50*time.Millisecond))
+
+	// 内容已经被删除的元素，Pop 和 BPop 都跳过
+	orphan, err := q.Push(ctx, "orphan", 0)
+	assert.NoError(t, err)
+	_, err = q.Push(ctx, "a", 1)
+	assert.NoError(t, err)
+	assert.NoError(t, comp.Client().HDel(ctx, q.keys.items, orphan).Err())
+	item, err := q.Pop(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", item.Payload)
+
+	skipWithoutBZPopMin(t, comp, q.keys.queue)
+	orphan, err = q.Push(ctx, "orphan", 0)
+	assert.NoError(t, err)
+	_, err = q.Push(ctx, "b", 1)
+	assert.NoError(t, err)
+	assert.NoError(t, comp.Client().HDel(ctx, q.keys.items, orphan).Err())
+	item, err = q.BPop(ctx)
+	assert.NoError(t, err)
+	if assert.NotNil(t, item) {
+		assert.Equal(t, "b", item.Payload)
+	}
+	n, err := q.Len(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestPriorityQueueBPop(t *testing.T) {
+	comp := newTestRedis(t, "redis.priorityTest")
+	ctx := context.Background()
+	q := newTestPriorityQueue(t, comp, WithPriorityQueueBlock(20*time.Millisecond))
+	skipWithoutBZPopMin(t, comp, q.keys.queue)
+
+	// 队列为空时阻塞，直到有新的元素
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = q.Push(context.Background(), "a", 3)
+	}()
+	item, err := q.BPop(ctx)
+	assert.NoError(t, err)
+	if assert.NotNil(t, item) {
+		assert.Equal(t, "a", item.Payload)
+		assert.Equal(t, int64(3), item.Priority)
+	}
+
+	// ctx 结束时返回 ctx 的错误
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	item, err = q.BPop(timeoutCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, item)
+}
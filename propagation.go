@@ -0,0 +1,112 @@
+package eredis
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/url"
+
+	"github.com/gotomicro/ego/core/etrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceMagic PUBLISH 消息携带 trace context 时的头部，后面是 2 字节的 header 长度、url 编码的 header 和原始消息
+var traceMagic = []byte{0xfe, 'e', 't'}
+
+// traceFields W3C trace context 使用的字段，stream 消息中以同名字段保存
+var traceFields = propagation.TraceContext{}.Fields()
+
+// startProducerSpan 创建 producer span，并把 trace context 注入到返回的 carrier 中
+func startProducerSpan(ctx context.Context, operation, destination string) (context.Context, trace.Span, propagation.MapCarrier) {
+	carrier := propagation.MapCarrier{}
+	ctx, span := etrace.NewTracer(trace.SpanKindProducer).Start(ctx, operation, carrier, trace.WithAttributes(
+		semconv.MessagingSystemKey.String("redis"),
+		semconv.MessagingDestinationKey.String(destination),
+	))
+	return ctx, span, carrier
+}
+
+// startConsumerSpan 从 carrier 中提取 trace context，在消费者自己的 ctx 上创建 consumer span，并通过 link 关联生产者 span。
+// 一条消息可能被多次投递，处理过程不作为生产者 trace 的一部分
+func startConsumerSpan(ctx context.Context, operation, destination string, carrier propagation.MapCarrier, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		semconv.MessagingSystemKey.String("redis"),
+		semconv.MessagingDestinationKey.String(destination),
+		semconv.MessagingOperationProcess,
+	)
+	opts := []trace.SpanStartOption{trace.WithAttributes(attrs...)}
+	producer := propagation.TraceContext{}.Extract(context.Background(), carrier)
+	if link := trace.LinkFromContext(producer); link.SpanContext.IsValid() {
+		opts = append(opts, trace.WithLinks(link))
+	}
+	return etrace.NewTracer(trace.SpanKindConsumer).Start(ctx, operation, nil, opts...)
+}
+
+// endSpan 结束 span，记录错误
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// wrapTraceEnvelope 在消息前加上 trace context
+func wrapTraceEnvelope(carrier propagation.MapCarrier, payload []byte) []byte {
+	values := url.Values{}
+	for k, v := range carrier {
+		values.Set(k, v)
+	}
+	header := values.Encode()
+	if len(carrier) == 0 || len(header) > 0xffff {
+		return payload
+	}
+	buf := make([]byte, 0, len(traceMagic)+2+len(header)+len(payload))
+	buf = append(buf, traceMagic...)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(header)))
+	buf = append(buf, header...)
+	return append(buf, payload...)
+}
+
+// unwrapTraceEnvelope 解析消息中的 trace context，没有 trace context 时原样返回
+func unwrapTraceEnvelope(payload string) (propagation.MapCarrier, string, bool) {
+	data := []byte(payload)
+	if !bytes.HasPrefix(data, traceMagic) || len(data) < len(traceMagic)+2 {
+		return nil, payload, false
+	}
+	n := int(binary.BigEndian.Uint16(data[len(traceMagic):]))
+	start := len(traceMagic) + 2
+	if len(data) < start+n {
+		return nil, payload, false
+	}
+	values, err := url.ParseQuery(string(data[start : start+n]))
+	if err != nil {
+		return nil, payload, false
+	}
+	carrier := propagation.MapCarrier{}
+	for k := range values {
+		carrier[k] = values.Get(k)
+	}
+	return carrier, payload[start+n:], true
+}
+
+// extractStreamTrace 取出 stream 消息中的 trace context 字段
+func extractStreamTrace(values map[string]interface{}) propagation.MapCarrier {
+	var carrier propagation.MapCarrier
+	for _, field := range traceFields {
+		v, ok := values[field].(string)
+		if !ok {
+			continue
+		}
+		if carrier == nil {
+			carrier = propagation.MapCarrier{}
+		}
+		carrier[field] = v
+		delete(values, field)
+	}
+	return carrier
+}
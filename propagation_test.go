@@ -0,0 +1,60 @@
+package eredis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gotomicro/ego/core/etrace"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracePropagation(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	recorder := tracetest.NewSpanRecorder()
+	etrace.SetGlobalTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, span, carrier := startProducerSpan(context.Background(), "PUBLISH orders", "orders")
+	span.End()
+	assert.NotEmpty(t, carrier.Get("traceparent"))
+	producer := trace.SpanContextFromContext(ctx)
+
+	// PUBLISH 消息
+	data := wrapTraceEnvelope(carrier, []byte("hello"))
+	got, payload, ok := unwrapTraceEnvelope(string(data))
+	assert.True(t, ok)
+	assert.Equal(t, "hello", payload)
+	assert.Equal(t, carrier.Get("traceparent"), got.Get("traceparent"))
+	_, payload, ok = unwrapTraceEnvelope("plain")
+	assert.False(t, ok)
+	assert.Equal(t, "plain", payload)
+
+	// stream 消息
+	values := map[string]interface{}{"orderId": "1", "traceparent": carrier.Get("traceparent")}
+	got = extractStreamTrace(values)
+	assert.Equal(t, map[string]interface{}{"orderId": "1"}, values)
+	ctx, span = startConsumerSpan(context.Background(), "XREADGROUP orders", "orders", got)
+	span.End()
+	assert.Nil(t, extractStreamTrace(map[string]interface{}{"orderId": "1"}))
+
+	// consumer span 在消费者自己的 trace 中，通过 link 关联生产者 span
+	consumer := trace.SpanContextFromContext(ctx)
+	assert.NotEqual(t, producer.TraceID(), consumer.TraceID())
+	ended := recorder.Ended()
+	if assert.Len(t, ended, 2) {
+		assert.Equal(t, trace.SpanKindConsumer, ended[1].SpanKind())
+		assert.False(t, ended[1].Parent().IsValid())
+		if assert.Len(t, ended[1].Links(), 1) {
+			assert.True(t, producer.Equal(ended[1].Links()[0].SpanContext.WithRemote(false)))
+		}
+	}
+
+	// carrier 中没有 trace context 时不添加 link
+	_, span = startConsumerSpan(context.Background(), "SUBSCRIBE orders", "orders", nil)
+	span.End()
+	assert.Empty(t, recorder.Ended()[2].Links())
+}
@@ -0,0 +1,355 @@
+package eredis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/gotomicro/ego/server"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// pubsubPingInterval 超过该时间没有收到消息时发送 PING 检查连接
+const pubsubPingInterval = 30 * time.Second
+
+var (
+	pubsubMessageCounter = emetric.CounterVecOpts{
+		Namespace: emetric.DefaultNamespace,
+		Name:      "client_redis_pubsub_message_total",
+		Help:      "redis pub/sub messages received by managed subscribers",
+		Labels:    []string{"name", "channel", "result"},
+	}.Build()
+
+	pubsubHandleHistogram = emetric.HistogramVecOpts{
+		Namespace: emetric.DefaultNamespace,
+		Name:      "client_redis_pubsub_handle_seconds",
+		Help:      "redis pub/sub message handle duration",
+		Labels:    []string{"name", "channel"},
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}.Build()
+)
+
+// Publish 使用 PUBLISH 发布消息，返回收到消息的订阅者数量。
+// 开启 EnableTracePropagation 时在消息前加上 trace context，Subscribe 收到消息时自动去掉，其他订阅方需要先升级。
+func (r *Component) Publish(ctx context.Context, channel string, message interface{}) (int64, error) {
+	return r.publish(ctx, "publish", channel, message)
+}
+
+// SPublish 使用 SPUBLISH 发布 sharded channel 消息，需要 redis 7.0 以上
+func (r *Component) SPublish(ctx context.Context, channel string, message interface{}) (int64, error) {
+	return r.publish(ctx, "spublish", channel, message)
+}
+
+func (r *Component) publish(ctx context.Context, name, channel string, message interface{}) (n int64, err error) {
+	if r.config.EnableTracePropagation {
+		if data, ok := valueBytes(message); ok {
+			var (
+				span    trace.Span
+				carrier propagation.MapCarrier
+			)
+			ctx, span, carrier = startProducerSpan(ctx, strings.ToUpper(name)+" "+channel, channel)
+			defer func() { endSpan(span, err) }()
+			message = wrapTraceEnvelope(carrier, data)
+		}
+	}
+	var cmd *redis.IntCmd
+	if name == "spublish" {
+		cmd = r.client.SPublish(ctx, channel, message)
+	} else {
+		cmd = r.client.Publish(ctx, channel, message)
+	}
+	return cmd.Val(), r.cmdErr(cmd)
+}
+
+// MessageHandler 处理订阅到的消息，返回的错误只记录日志和监控
+type MessageHandler func(ctx context.Context, msg *redis.Message) error
+
+// SubscribeOption 订阅选项
+type SubscribeOption func(s *Subscriber)
+
+// WithSubscribePatterns 使用 PSUBSCRIBE 按模式订阅
+func WithSubscribePatterns() SubscribeOption {
+	return func(s *Subscriber) {
+		s.patterns = true
+	}
+}
+
+// WithSubscribeSharded 使用 SSUBSCRIBE 订阅 sharded channel，需要 redis 7.0 以上，cluster 模式下按 slot 分别订阅
+func WithSubscribeSharded() SubscribeOption {
+	return func(s *Subscriber) {
+		s.sharded = true
+	}
+}
+
+// WithSubscribeConcurrency handler 的最大并发数，默认 1，即按顺序处理
+func WithSubscribeConcurrency(n int) SubscribeOption {
+	return func(s *Subscriber) {
+		s.concurrency = n
+	}
+}
+
+// WithSubscribeBuffer 等待处理的消息数量上限，超过之后丢弃新消息，默认 1000
+func WithSubscribeBuffer(n int) SubscribeOption {
+	return func(s *Subscriber) {
+		s.buffer = n
+	}
+}
+
+// Subscriber 托管的订阅，由 Subscribe 创建。
+// 连接断开之后自动重连并重新订阅，重连期间发布的消息会丢失。
+// 实现了 ego 的 server.Server 接口，可以通过 ego.New().Serve(subscriber) 随应用优雅退出。
+type Subscriber struct {
+	comp        *Component
+	channels    []string
+	handler     MessageHandler
+	patterns    bool
+	sharded     bool
+	concurrency int
+	buffer      int
+	logger      *elog.Component
+
+	client  redis.UniversalClient
+	pubsubs []*redis.PubSub
+	queue   chan *redis.Message
+
+	ctx          context.Context // ctx 接收消息使用，取消订阅时取消
+	cancel       context.CancelFunc
+	handleCtx    context.Context // handleCtx 处理消息使用，Stop 或者优雅退出超时时取消
+	handleCancel context.CancelFunc
+	closeOnce    sync.Once
+	receivers    sync.WaitGroup
+	workers      sync.WaitGroup
+	done         chan struct{}
+
+	consumerServer
+}
+
+var _ server.Server = (*Subscriber)(nil)
+
+// Subscribe 订阅 channels 并在后台处理消息，使用专用的连接，不经过组件的拦截器。
+// 首次订阅失败时返回错误，使用完毕后需要调用 Close。
+func (r *Component) Subscribe(ctx context.Context, channels []string, handler MessageHandler, opts ...SubscribeOption) (*Subscriber, error) {
+	s := &Subscriber{
+		comp:           r,
+		channels:       channels,
+		handler:        handler,
+		concurrency:    1,
+		buffer:         1000,
+		done:           make(chan struct{}),
+		consumerServer: consumerServer{name: r.name, scheme: "redis-pubsub", address: fmt.Sprint(channels)},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if len(channels) == 0 || (s.patterns && s.sharded) {
+		return nil, ErrInvalidParams
+	}
+	if s.concurrency <= 0 {
+		s.concurrency = 1
+	}
+	if s.buffer <= 0 {
+		s.buffer = 1
+	}
+	s.logger = r.logger.With(elog.Any("channels", channels))
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.handleCtx, s.handleCancel = context.WithCancel(context.Background())
+	s.client = r.newSubscriber(nil)
+
+	if err := s.subscribe(ctx); err != nil {
+		s.cancel()
+		s.handleCancel()
+		s.closePubSubs()
+		return nil, err
+	}
+
+	s.queue = make(chan *redis.Message, s.buffer)
+	for i := 0; i < s.concurrency; i++ {
+		s.workers.Add(1)
+		go s.work()
+	}
+	for _, pubsub := range s.pubsubs {
+		s.receivers.Add(1)
+		go s.receive(pubsub)
+	}
+	go func() {
+		s.receivers.Wait()
+		close(s.queue)
+		s.workers.Wait()
+		close(s.done)
+	}()
+	return s, nil
+}
+
+// subscribe 建立订阅，cluster 模式下 sharded channel 按 slot 分组，每组使用一个连接
+func (s *Subscriber) subscribe(ctx context.Context) error {
+	switch {
+	case s.patterns:
+		pubsub := s.client.PSubscribe(ctx)
+		s.pubsubs = append(s.pubsubs, pubsub)
+		return s.comp.wrapErr("psubscribe", pubsub.PSubscribe(ctx, s.channels...))
+	case s.sharded:
+		groups := []*slotGroup{{keys: s.channels}}
+		if s.comp.Cluster() != nil {
+			groups = groupKeysBySlot("", s.channels)
+		}
+		for _, group := range groups {
+			pubsub := s.client.SSubscribe(ctx)
+			s.pubsubs = append(s.pubsubs, pubsub)
+			if err := pubsub.SSubscribe(ctx, group.keys...); err != nil {
+				return s.comp.wrapErr("ssubscribe", err)
+			}
+		}
+		return nil
+	default:
+		pubsub := s.client.Subscribe(ctx)
+		s.pubsubs = append(s.pubsubs, pubsub)
+		return s.comp.wrapErr("subscribe", pubsub.Subscribe(ctx, s.channels...))
+	}
+}
+
+// receive 接收消息并放入队列，队列满时丢弃。
+// 出错时 go-redis 会在下一次接收时重连并重新订阅。
+func (s *Subscriber) receive(pubsub *redis.PubSub) {
+	defer s.receivers.Done()
+	backoff := 100 * time.Millisecond
+	for {
+		msg, err := pubsub.ReceiveTimeout(s.ctx, pubsubPingInterval)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			if IsTimeout(err) {
+				// PING 失败时连接被关闭，下一次接收时重连
+				if err := pubsub.Ping(s.ctx); err != nil {
+					s.logger.Warn("pubsub ping fail", elog.FieldName(s.comp.name), elog.FieldErr(err))
+				}
+				continue
+			}
+			s.logger.Warn("pubsub receive fail, resubscribe", elog.FieldName(s.comp.name), elog.FieldErr(err))
+			if !s.sleep(backoff) {
+				return
+			}
+			if backoff *= 2; backoff > 5*time.Second {
+				backoff = 5 * time.Second
+			}
+			continue
+		}
+		backoff = 100 * time.Millisecond
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			s.logger.Info("pubsub subscribed", elog.FieldName(s.comp.name), elog.String("kind", m.Kind), elog.String("channel", m.Channel))
+		case *redis.Message:
+			select {
+			case s.queue <- m:
+			default:
+				pubsubMessageCounter.Inc(s.comp.name, messageChannel(m), "drop")
+			}
+		}
+	}
+}
+
+// sleep 等待重试，关闭时返回 false
+func (s *Subscriber) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// work 处理队列中的消息
+func (s *Subscriber) work() {
+	defer s.workers.Done()
+	for msg := range s.queue {
+		s.handle(msg)
+	}
+}
+
+// handle 处理一条消息，消息中带有 trace context 时去掉 trace context 并创建 consumer span
+func (s *Subscriber) handle(msg *redis.Message) {
+	channel := messageChannel(msg)
+	start := time.Now()
+	ctx := s.handleCtx
+	var span trace.Span
+	if carrier, payload, ok := unwrapTraceEnvelope(msg.Payload); ok {
+		msg.Payload = payload
+		ctx, span = startConsumerSpan(ctx, "SUBSCRIBE "+channel, msg.Channel, carrier)
+	}
+	err := callHandler("pubsub", func() error { return s.handler(ctx, msg) })
+	if span != nil {
+		endSpan(span, err)
+	}
+	pubsubHandleHistogram.Observe(time.Since(start).Seconds(), s.comp.name, channel)
+	if err != nil {
+		pubsubMessageCounter.Inc(s.comp.name, channel, "error")
+		s.logger.Error("handle pubsub message fail", elog.FieldName(s.comp.name), elog.String("channel", msg.Channel), elog.FieldErr(err))
+		return
+	}
+	pubsubMessageCounter.Inc(s.comp.name, channel, "ok")
+}
+
+// messageChannel 监控使用的 channel，按模式订阅时使用模式，避免 label 过多
+func messageChannel(msg *redis.Message) string {
+	if msg.Pattern != "" {
+		return msg.Pattern
+	}
+	return msg.Channel
+}
+
+func (s *Subscriber) closePubSubs() {
+	for _, pubsub := range s.pubsubs {
+		_ = pubsub.Close()
+	}
+	_ = s.client.Close()
+}
+
+// stopReceive 取消订阅，已经收到的消息继续处理
+func (s *Subscriber) stopReceive() {
+	s.closeOnce.Do(func() {
+		// 先取消 receive 的 context，避免把关闭连接当作断线重连
+		s.cancel()
+		s.closePubSubs()
+	})
+}
+
+// Close 取消订阅，等待已经收到的消息处理完成
+func (s *Subscriber) Close() error {
+	s.stopReceive()
+	<-s.done
+	s.handleCancel()
+	return nil
+}
+
+// Start Subscribe 时已经开始订阅，Start 阻塞直到关闭
+func (s *Subscriber) Start() error {
+	<-s.done
+	return nil
+}
+
+// Stop 取消订阅，正在处理的消息的 context 被取消
+func (s *Subscriber) Stop() error {
+	s.stopReceive()
+	s.handleCancel()
+	return nil
+}
+
+// GracefulStop 取消订阅，等待已经收到的消息处理完成，最长等待到 ctx 结束
+func (s *Subscriber) GracefulStop(ctx context.Context) error {
+	s.stopReceive()
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+	}
+	s.handleCancel()
+	return nil
+}
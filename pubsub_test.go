@@ -0,0 +1,89 @@
+package eredis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribeInvalidParams(t *testing.T) {
+	r := &Component{}
+	handler := func(ctx context.Context, msg *redis.Message) error { return nil }
+	_, err := r.Subscribe(context.Background(), nil, handler)
+	assert.ErrorIs(t, err, ErrInvalidParams)
+	_, err = r.Subscribe(context.Background(), []string{"a"}, handler, WithSubscribePatterns(), WithSubscribeSharded())
+	assert.ErrorIs(t, err, ErrInvalidParams)
+
+	assert.Equal(t, "news.*", messageChannel(&redis.Message{Channel: "news.sport", Pattern: "news.*"}))
+	assert.Equal(t, "news", messageChannel(&redis.Message{Channel: "news"}))
+}
+
+func TestSubscriber(t *testing.T) {
+	comp := newTestRedis(t, "redis.pubsubTest")
+	ctx := context.Background()
+	channel := newTestKey(t, comp, "pubsub")
+
+	received := make(chan string, 10)
+	release := make(chan struct{})
+	s, err := comp.Subscribe(ctx, []string{channel}, func(ctx context.Context, msg *redis.Message) error {
+		switch msg.Payload {
+		case "panic":
+			panic("boom")
+		case "slow":
+			<-release
+		}
+		received <- msg.Payload
+		return nil
+	})
+	assert.NoError(t, err)
+	started := make(chan struct{})
+	go func() {
+		_ = s.Start()
+		close(started)
+	}()
+
+	// handler panic 之后继续处理后续的消息，并且按顺序处理
+	for _, payload := range []string{"panic", "a", "b"} {
+		_, err := comp.Publish(ctx, channel, payload)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, "a", waitMessage(t, received))
+	assert.Equal(t, "b", waitMessage(t, received))
+
+	// 优雅退出时等待正在处理的消息完成
+	_, err = comp.Publish(ctx, channel, "slow")
+	assert.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	stopped := make(chan struct{})
+	go func() {
+		_ = s.GracefulStop(context.Background())
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("GracefulStop returned before the message was handled")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	assert.Equal(t, "slow", waitMessage(t, received))
+	<-stopped
+	<-started
+
+	// 取消订阅之后不再收到消息
+	n, err := comp.Publish(ctx, channel, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func waitMessage(t *testing.T, ch <-chan string) string {
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for message")
+		return ""
+	}
+}
@@ -0,0 +1,427 @@
+package eredis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/gotomicro/ego/server"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	queueDepthGauge = emetric.GaugeVecOpts{
+		Namespace: emetric.DefaultNamespace,
+		Name:      "client_redis_queue_depth",
+		Help:      "redis queue messages waiting to be received",
+		Labels:    []string{"name", "queue"},
+	}.Build()
+	queueInflightGauge = emetric.GaugeVecOpts{
+		Namespace: emetric.DefaultNamespace,
+		Name:      "client_redis_queue_inflight",
+		Help:      "redis queue messages received but not acked",
+		Labels:    []string{"name", "queue"},
+	}.Build()
+)
+
+var (
+	// luaReliableNack 把消息从 processing 列表放回 pending 列表，消息已经被回收时不做处理
+	luaReliableNack = redis.NewScript(`
+if redis.call("lrem", KEYS[1], 1, ARGV[1]) == 1 then
+  redis.call("lpush", KEYS[2], ARGV[1])
+  return 1
+end
+return 0`)
+
+	// luaReliableReap 心跳超时时把 consumer 的 processing 列表放回 pending 列表，返回回收的数量
+	luaReliableReap = redis.NewScript(`
+local beat = redis.call("zscore", KEYS[1], ARGV[1])
+if beat and tonumber(beat) > tonumber(ARGV[2]) then return 0 end
+local n = 0
+while redis.call("rpoplpush", KEYS[2], KEYS[3]) do n = n + 1 end
+redis.call("zrem", KEYS[1], ARGV[1])
+return n`)
+)
+
+// ReliableQueueOption 可靠队列选项
+type ReliableQueueOption func(q *ReliableQueue)
+
+// WithReliableQueueWorkers 并发处理消息的 worker 数量，每个 worker 有独立的 processing 列表，默认 1
+func WithReliableQueueWorkers(workers int) ReliableQueueOption {
+	return func(q *ReliableQueue) {
+		q.workers = workers
+	}
+}
+
+// WithReliableQueueBlock BLMOVE 的阻塞时间，默认 5s
+func WithReliableQueueBlock(block time.Duration) ReliableQueueOption {
+	return func(q *ReliableQueue) {
+		q.block = block
+	}
+}
+
+// WithReliableQueueHeartbeat 心跳间隔和超时时间，超过 timeout 没有心跳的 consumer 的消息会被放回队列，默认 5s、30s
+func WithReliableQueueHeartbeat(interval, timeout time.Duration) ReliableQueueOption {
+	return func(q *ReliableQueue) {
+		q.heartbeat = interval
+		q.heartbeatTimeout = timeout
+	}
+}
+
+// WithReliableQueueMaxRetries 最大重试次数，超过之后移动到死信 hash，默认 3，小于 0 表示不限制
+func WithReliableQueueMaxRetries(retries int64) ReliableQueueOption {
+	return func(q *ReliableQueue) {
+		q.maxRetries = retries
+	}
+}
+
+// ReliableQueue 基于 LIST 的可靠队列，由 NewReliableQueue 创建。
+// 消费者使用 BLMOVE 把消息原子地移动到自己的 processing 列表，ack 之后才删除，
+// consumer 崩溃之后由其他实例的 reaper 根据心跳把消息放回队列。
+// 所有 key 使用 {name} 作为 hash tag，cluster 模式下位于同一个 slot：
+//
+//	{name}:pending               LIST 等待处理的消息 ID
+//	{name}:processing:<consumer> LIST consumer 正在处理的消息 ID
+//	{name}:consumers             ZSET consumer 的最近心跳时间
+//	{name}:messages              HASH 消息 ID 到内容
+//	{name}:attempts              HASH 消息 ID 到投递次数
+//	{name}:dead                  HASH 超过最大重试次数的消息
+type ReliableQueue struct {
+	comp             *Component
+	name             string
+	prefix           string
+	workers          int
+	block            time.Duration
+	heartbeat        time.Duration
+	heartbeatTimeout time.Duration
+	maxRetries       int64
+}
+
+// NewReliableQueue 创建可靠队列，需要 Redis 6.2 以上版本
+func (r *Component) NewReliableQueue(name string, opts ...ReliableQueueOption) *ReliableQueue {
+	q := &ReliableQueue{
+		comp:             r,
+		name:             name,
+		prefix:           "{" + name + "}:",
+		workers:          1,
+		block:            5 * time.Second,
+		heartbeat:        5 * time.Second,
+		heartbeatTimeout: 30 * time.Second,
+		maxRetries:       3,
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if q.workers <= 0 {
+		q.workers = 1
+	}
+	if q.heartbeat <= 0 {
+		q.heartbeat = 5 * time.Second
+	}
+	if q.heartbeatTimeout < q.heartbeat {
+		q.heartbeatTimeout = 6 * q.heartbeat
+	}
+	return q
+}
+
+func (q *ReliableQueue) key(name string) string {
+	return q.prefix + name
+}
+
+func (q *ReliableQueue) processingKey(consumer string) string {
+	return q.prefix + "processing:" + consumer
+}
+
+// Push 添加消息，返回消息 ID
+func (q *ReliableQueue) Push(ctx context.Context, payload string) (string, error) {
+	id, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = q.comp.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, q.key("messages"), id, payload)
+		pipe.LPush(ctx, q.key("pending"), id)
+		return nil
+	})
+	if err != nil {
+		return "", q.comp.wrapErr("lpush", err)
+	}
+	return id, nil
+}
+
+// Heartbeat 更新 consumer 的心跳，Receive 之前需要先调用，之后至少每个心跳间隔调用一次
+func (q *ReliableQueue) Heartbeat(ctx context.Context, consumers ...string) error {
+	members := make([]redis.Z, 0, len(consumers))
+	now := float64(time.Now().UnixMilli())
+	for _, consumer := range consumers {
+		members = append(members, redis.Z{Score: now, Member: consumer})
+	}
+	return q.comp.wrapErr("zadd", q.comp.client.ZAdd(ctx, q.key("consumers"), members...).Err())
+}
+
+// Receive 阻塞等待一条消息并移动到 consumer 的 processing 列表，超时时返回 nil。
+// 处理完成之后需要调用 Ack 或者 Nack
+func (q *ReliableQueue) Receive(ctx context.Context, consumer string) (*Job, error) {
+	processing := q.processingKey(consumer)
+	for {
+		id, err := q.comp.client.BLMove(ctx, q.key("pending"), processing, "RIGHT", "LEFT", q.block).Result()
+		if IsNil(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, q.comp.wrapErr("blmove", err)
+		}
+		var payload *redis.StringCmd
+		var attempts *redis.IntCmd
+		_, err = q.comp.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			payload = pipe.HGet(ctx, q.key("messages"), id)
+			attempts = pipe.HIncrBy(ctx, q.key("attempts"), id, 1)
+			return nil
+		})
+		if IsNil(err) {
+			// 消息内容已经被删除，丢弃
+			_, err = q.comp.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.LRem(ctx, processing, 1, id)
+				pipe.HDel(ctx, q.key("attempts"), id)
+				return nil
+			})
+			if err != nil {
+				return nil, q.comp.wrapErr("lrem", err)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, q.comp.wrapErr("hget", err)
+		}
+		job := &Job{ID: id, Payload: payload.Val(), Attempts: attempts.Val()}
+		// consumer 在处理最后一次重试时崩溃，不再投递
+		if q.maxRetries >= 0 && job.Attempts > q.maxRetries+1 {
+			if err := q.deadLetter(ctx, consumer, id); err != nil {
+				return nil, err
+			}
+			queueJobCounter.Inc(q.comp.name, q.name, "dead_letter")
+			continue
+		}
+		return job, nil
+	}
+}
+
+// Ack 消息处理完成，删除消息
+func (q *ReliableQueue) Ack(ctx context.Context, consumer string, id string) error {
+	_, err := q.comp.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, q.processingKey(consumer), 1, id)
+		pipe.HDel(ctx, q.key("messages"), id)
+		pipe.HDel(ctx, q.key("attempts"), id)
+		return nil
+	})
+	return q.comp.wrapErr("lrem", err)
+}
+
+// Nack 消息处理失败，未超过最大重试次数时放回队列，否则移动到死信 hash，返回是否进入死信
+func (q *ReliableQueue) Nack(ctx context.Context, consumer string, job *Job) (bool, error) {
+	if q.maxRetries >= 0 && job.Attempts > q.maxRetries {
+		return true, q.deadLetter(ctx, consumer, job.ID)
+	}
+	keys := []string{q.processingKey(consumer), q.key("pending")}
+	return false, q.comp.wrapErr("evalsha", luaReliableNack.Run(ctx, q.comp.client, keys, job.ID).Err())
+}
+
+func (q *ReliableQueue) deadLetter(ctx context.Context, consumer string, id string) error {
+	keys := []string{q.processingKey(consumer), q.key("messages"), q.key("attempts"), q.key("dead")}
+	return q.comp.wrapErr("evalsha", luaQueueDead.Run(ctx, q.comp.client, keys, id).Err())
+}
+
+// reap 把心跳早于 deadline 的 consumer 的消息放回队列，返回回收的数量
+func (q *ReliableQueue) reap(ctx context.Context, consumer string, deadline time.Time) (int64, error) {
+	keys := []string{q.key("consumers"), q.processingKey(consumer), q.key("pending")}
+	n, err := luaReliableReap.Run(ctx, q.comp.client, keys, consumer, deadline.UnixMilli()).Int64()
+	return n, q.comp.wrapErr("evalsha", err)
+}
+
+// Reap 回收心跳超时的 consumer 正在处理的消息，返回回收的数量，worker 会定期调用
+func (q *ReliableQueue) Reap(ctx context.Context) (int64, error) {
+	deadline := time.Now().Add(-q.heartbeatTimeout)
+	consumers, err := q.comp.client.ZRangeByScore(ctx, q.key("consumers"), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: fmt.Sprint(deadline.UnixMilli()),
+	}).Result()
+	if err != nil {
+		return 0, q.comp.wrapErr("zrangebyscore", err)
+	}
+	var total int64
+	for _, consumer := range consumers {
+		n, err := q.reap(ctx, consumer, deadline)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// Stats 返回等待处理和正在处理的消息数量
+func (q *ReliableQueue) Stats(ctx context.Context) (depth int64, inflight int64, err error) {
+	consumers, err := q.comp.client.ZRange(ctx, q.key("consumers"), 0, -1).Result()
+	if err != nil {
+		return 0, 0, q.comp.wrapErr("zrange", err)
+	}
+	var pending *redis.IntCmd
+	lens := make([]*redis.IntCmd, 0, len(consumers))
+	_, err = q.comp.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pending = pipe.LLen(ctx, q.key("pending"))
+		for _, consumer := range consumers {
+			lens = append(lens, pipe.LLen(ctx, q.processingKey(consumer)))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, q.comp.wrapErr("llen", err)
+	}
+	for _, l := range lens {
+		inflight += l.Val()
+	}
+	return pending.Val(), inflight, nil
+}
+
+// NewWorker 创建处理消息的 worker 池，handler 返回 nil 时 Ack，返回错误时 Nack
+func (q *ReliableQueue) NewWorker(handler JobHandler) *ReliableQueueWorker {
+	hostname, _ := os.Hostname()
+	token, _ := randomToken()
+	w := &ReliableQueueWorker{
+		queue:          q,
+		handler:        handler,
+		logger:         q.comp.logger.With(elog.String("queue", q.name)),
+		consumerServer: consumerServer{name: q.comp.name, scheme: "redis-reliable-queue", address: q.name},
+		workerGroup:    newWorkerGroup(),
+	}
+	for i := 0; i < q.workers; i++ {
+		w.consumers = append(w.consumers, fmt.Sprintf("%s-%d-%s-%d", hostname, os.Getpid(), token, i))
+	}
+	return w
+}
+
+var _ server.Server = (*ReliableQueueWorker)(nil)
+
+// ReliableQueueWorker 可靠队列的 worker 池，由 ReliableQueue.NewWorker 创建。
+// 实现了 ego 的 server.Server 接口，可以通过 ego.New().Serve(worker) 启动，随应用优雅退出。
+// 同时负责心跳、回收其他实例崩溃之后遗留的消息以及上报队列长度。
+type ReliableQueueWorker struct {
+	queue     *ReliableQueue
+	handler   JobHandler
+	logger    *elog.Component
+	consumers []string
+
+	consumerServer
+	workerGroup
+}
+
+// Start 启动 worker，阻塞直到停止
+func (w *ReliableQueueWorker) Start() error {
+	if err := w.queue.Heartbeat(w.ctx, w.consumers...); err != nil {
+		return err
+	}
+	w.run(w.heartbeat)
+	w.run(w.reap)
+	for _, consumer := range w.consumers {
+		consumer := consumer
+		w.run(func() { w.work(consumer) })
+	}
+	w.wg.Wait()
+	w.release()
+	return nil
+}
+
+// Stop 立即停止，正在处理的消息的 context 被取消
+func (w *ReliableQueueWorker) Stop() error {
+	w.stop()
+	return nil
+}
+
+// GracefulStop 停止接收新消息，等待正在处理的消息完成，ctx 结束时取消正在处理的消息
+func (w *ReliableQueueWorker) GracefulStop(ctx context.Context) error {
+	w.gracefulStop(ctx)
+	return nil
+}
+
+// release 停止之后把没有处理完的消息放回队列，注销 consumer
+func (w *ReliableQueueWorker) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	deadline := time.Now().Add(time.Hour)
+	for _, consumer := range w.consumers {
+		if n, err := w.queue.reap(ctx, consumer, deadline); err != nil {
+			w.logger.Error("release consumer fail", elog.FieldName(w.queue.comp.name), elog.String("consumer", consumer), elog.FieldErr(err))
+		} else if n > 0 {
+			queueJobCounter.Add(float64(n), w.queue.comp.name, w.queue.name, "requeue")
+		}
+	}
+}
+
+// heartbeat 定期更新心跳
+func (w *ReliableQueueWorker) heartbeat() {
+	for w.sleep(w.queue.heartbeat) {
+		if err := w.queue.Heartbeat(w.ctx, w.consumers...); err != nil {
+			w.logger.Error("queue heartbeat fail", elog.FieldName(w.queue.comp.name), elog.FieldErr(err))
+		}
+	}
+}
+
+// reap 定期回收心跳超时的 consumer 的消息，上报队列长度
+func (w *ReliableQueueWorker) reap() {
+	name := w.queue.comp.name
+	for w.sleep(w.queue.heartbeat) {
+		n, err := w.queue.Reap(w.ctx)
+		if err != nil {
+			w.logger.Error("reap queue fail", elog.FieldName(name), elog.FieldErr(err))
+		}
+		if n > 0 {
+			queueJobCounter.Add(float64(n), name, w.queue.name, "requeue")
+			w.logger.Warn("requeue messages from dead consumers", elog.FieldName(name), elog.Int64("count", n))
+		}
+		depth, inflight, err := w.queue.Stats(w.ctx)
+		if err != nil {
+			w.logger.Error("queue stats fail", elog.FieldName(name), elog.FieldErr(err))
+			continue
+		}
+		queueDepthGauge.Set(float64(depth), name, w.queue.name)
+		queueInflightGauge.Set(float64(inflight), name, w.queue.name)
+	}
+}
+
+// work 接收消息并执行
+func (w *ReliableQueueWorker) work(consumer string) {
+	name := w.queue.comp.name
+	for !w.stopped() {
+		job, err := w.queue.Receive(w.ctx, consumer)
+		if err != nil {
+			w.logger.Error("receive message fail", elog.FieldName(name), elog.String("consumer", consumer), elog.FieldErr(err))
+			if !w.sleep(time.Second) {
+				return
+			}
+			continue
+		}
+		if job == nil {
+			continue
+		}
+		if err := callHandler("job", func() error { return w.handler(w.ctx, job) }); err != nil {
+			dead, e := w.queue.Nack(w.ctx, consumer, job)
+			result := "error"
+			if dead {
+				result = "dead_letter"
+			}
+			queueJobCounter.Inc(name, w.queue.name, result)
+			w.logger.Error("handle message fail", elog.FieldName(name), elog.String("id", job.ID), elog.Int64("attempts", job.Attempts), elog.Any("dead", dead), elog.FieldErr(err))
+			if e != nil {
+				w.logger.Error("nack message fail", elog.FieldName(name), elog.String("id", job.ID), elog.FieldErr(e))
+			}
+			continue
+		}
+		queueJobCounter.Inc(name, w.queue.name, "ok")
+		if err := w.queue.Ack(w.ctx, consumer, job.ID); err != nil {
+			w.logger.Error("ack message fail", elog.FieldName(name), elog.String("id", job.ID), elog.FieldErr(err))
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package eredis
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestReliableQueue 创建使用唯一名称的可靠队列，测试结束后删除所有 key
+func newTestReliableQueue(t *testing.T, comp *Component, opts ...ReliableQueueOption) *ReliableQueue {
+	name := newTestKey(t, comp, "reliable")
+	q := comp.NewReliableQueue(name, opts...)
+	t.Cleanup(func() {
+		ctx := context.Background()
+		consumers, _ := comp.Client().ZRange(ctx, q.key("consumers"), 0, -1).Result()
+		keys := []string{q.key("pending"), q.key("consumers"), q.key("messages"), q.key("attempts"), q.key("dead")}
+		for _, consumer := range consumers {
+			keys = append(keys, q.processingKey(consumer))
+		}
+		_, _ = comp.Del(ctx, keys...)
+	})
+	return q
+}
+
+func TestReliableQueue(t *testing.T) {
+	comp := newTestRedis(t, "redis.reliableTest")
+	ctx := context.Background()
+	q := newTestReliableQueue(t, comp,
+		WithReliableQueueBlock(50*time.Millisecond),
+		WithReliableQueueHeartbeat(10*time.Millisecond, 50*time.Millisecond),
+		WithReliableQueueMaxRetries(1),
+	)
+	assert.NoError(t, q.Heartbeat(ctx, "c1"))
+
+	a, err := q.Push(ctx, "a")
+	assert.NoError(t, err)
+	b, err := q.Push(ctx, "b")
+	assert.NoError(t, err)
+
+	// 按写入顺序接收，Nack 之后放回队列末尾
+	job, err := q.Receive(ctx, "c1")
+	assert.NoError(t, err)
+	assert.Equal(t, &Job{ID: a, Payload: "a", Attempts: 1}, job)
+	dead, err := q.Nack(ctx, "c1", job)
+	assert.NoError(t, err)
+	assert.False(t, dead)
+
+	job, err = q.Receive(ctx, "c1")
+	assert.NoError(t, err)
+	assert.Equal(t, b, job.ID)
+	assert.NoError(t, q.Ack(ctx, "c1", job.ID))
+
+	// 超过最大重试次数之后 Nack 移动到死信 hash
+	job, err = q.Receive(ctx, "c1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), job.Attempts)
+	dead, err = q.Nack(ctx, "c1", job)
+	assert.NoError(t, err)
+	assert.True(t, dead)
+	payload, err := comp.HGet(ctx, q.key("dead"), a)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", payload)
+
+	// 队列为空时阻塞 block 之后返回 nil
+	job, err = q.Receive(ctx, "c1")
+	assert.NoError(t, err)
+	assert.Nil(t, job)
+}
+
+func TestReliableQueueReap(t *testing.T) {
+	comp := newTestRedis(t, "redis.reliableTest")
+	ctx := context.Background()
+	q := newTestReliableQueue(t, comp,
+		WithReliableQueueBlock(50*time.Millisecond),
+		WithReliableQueueHeartbeat(10*time.Millisecond, 50*time.Millisecond),
+		WithReliableQueueMaxRetries(1),
+	)
+	id, err := q.Push(ctx, "a")
+	assert.NoError(t, err)
+
+	// consumer 心跳超时之后，正在处理的消息被放回队列
+	for i, consumer := range []string{"c1", "c2"} {
+		assert.NoError(t, q.Heartbeat(ctx, consumer))
+		job, err := q.Receive(ctx, consumer)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(i+1), job.Attempts)
+
+		n, err := q.Reap(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), n)
+		time.Sleep(60 * time.Millisecond)
+		n, err = q.Reap(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		depth, inflight, err := q.Stats(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), depth)
+		assert.Equal(t, int64(0), inflight)
+	}
+
+	// 一直崩溃的消息在 Receive 时移动到死信 hash
+	assert.NoError(t, q.Heartbeat(ctx, "c3"))
+	job, err := q.Receive(ctx, "c3")
+	assert.NoError(t, err)
+	assert.Nil(t, job)
+	payload, err := comp.HGet(ctx, q.key("dead"), id)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", payload)
+}
+
+func TestReliableQueueWorker(t *testing.T) {
+	comp := newTestRedis(t, "redis.reliableTest")
+	ctx := context.Background()
+	q := newTestReliableQueue(t, comp,
+		WithReliableQueueWorkers(2),
+		WithReliableQueueBlock(20*time.Millisecond),
+		WithReliableQueueHeartbeat(10*time.Millisecond, 50*time.Millisecond),
+	)
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	w := q.NewWorker(func(ctx context.Context, job *Job) error {
+		mu.Lock()
+		received = append(received, job.Payload)
+		mu.Unlock()
+		return nil
+	})
+	stopped := make(chan struct{})
+	go func() {
+		assert.NoError(t, w.Start())
+		close(stopped)
+	}()
+
+	for _, payload := range []string{"a", "b", "c"} {
+		_, err := q.Push(ctx, payload)
+		assert.NoError(t, err)
+	}
+	assert.Eventually(t, func() bool {
+		n, err := comp.Client().HLen(ctx, q.key("messages")).Result()
+		return err == nil && n == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, received)
+	mu.Unlock()
+
+	// 停止之后注销 consumer
+	assert.NoError(t, w.GracefulStop(ctx))
+	<-stopped
+	n, err := comp.Client().ZCard(ctx, q.key("consumers")).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
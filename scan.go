@@ -0,0 +1,470 @@
+package eredis
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultScanCount     = 100
+	defaultScanBatchSize = 100
+)
+
+// ScanOptions SCAN/HSCAN/SSCAN/ZSCAN 以及按模式批量操作的选项
+type ScanOptions struct {
+	Count     int64              // Count 每次 SCAN 的 COUNT 提示，默认 100
+	Type      string             // Type 仅返回指定类型的 key，例如 string、hash，需要 redis 6.0 以上，只对 ScanKeys 生效
+	Interval  time.Duration      // Interval 两次 SCAN 之间的最小间隔，用于限速，默认不限速
+	BatchSize int                // BatchSize DeleteByPattern、ExpireByPattern 每批处理的 key 数量，默认 100
+	Progress  func(ScanProgress) // Progress DeleteByPattern、ExpireByPattern 每处理完一批后的进度回调
+}
+
+// ScanProgress 按模式批量操作的进度
+type ScanProgress struct {
+	Node     string // Node 当前正在扫描的节点
+	Scanned  int64  // Scanned 已经扫描到的 key 数量
+	Affected int64  // Affected 已经删除或设置过期时间成功的 key 数量
+}
+
+func (o *ScanOptions) withDefaults() *ScanOptions {
+	opts := ScanOptions{}
+	if o != nil {
+		opts = *o
+	}
+	if opts.Count <= 0 {
+		opts.Count = defaultScanCount
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultScanBatchSize
+	}
+	return &opts
+}
+
+// scanner 负责按游标分页拉取数据，并按 interval 限速
+type scanner struct {
+	base     context.Context // base 创建迭代器时传入的 ctx，结束之后停止遍历
+	scan     func(ctx context.Context, cursor uint64) ([]string, uint64, error)
+	interval time.Duration
+	cursor   uint64
+	started  bool
+	last     time.Time
+}
+
+// nextPage 拉取下一页，游标回到 0 时返回 false
+func (s *scanner) nextPage(ctx context.Context) ([]string, bool, error) {
+	if s.started && s.cursor == 0 {
+		return nil, false, nil
+	}
+	var baseDone <-chan struct{}
+	if s.base != nil {
+		if err := s.base.Err(); err != nil {
+			return nil, false, err
+		}
+		baseDone = s.base.Done()
+	}
+	if s.interval > 0 && !s.last.IsZero() {
+		if wait := s.interval - time.Since(s.last); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, false, ctx.Err()
+			case <-baseDone:
+				timer.Stop()
+				return nil, false, s.base.Err()
+			case <-timer.C:
+			}
+		}
+	}
+	page, cursor, err := s.scan(ctx, s.cursor)
+	s.last = time.Now()
+	if err != nil {
+		return nil, false, err
+	}
+	s.started = true
+	s.cursor = cursor
+	return page, true, nil
+}
+
+// pageIterator 在 scanner 之上按 stride 逐个返回元素，HSCAN/ZSCAN 的 stride 为 2
+type pageIterator struct {
+	scanner *scanner
+	stride  int
+	page    []string
+	pos     int
+	val     []string
+	err     error
+}
+
+func (it *pageIterator) next(ctx context.Context) bool {
+	for it.err == nil {
+		if it.pos+it.stride <= len(it.page) {
+			it.val = it.page[it.pos : it.pos+it.stride]
+			it.pos += it.stride
+			return true
+		}
+		page, ok, err := it.scanner.nextPage(ctx)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if !ok {
+			return false
+		}
+		it.page, it.pos = page, 0
+	}
+	return false
+}
+
+// KeyIterator 遍历所有 master 节点的 key，由 ScanKeys 创建
+type KeyIterator struct {
+	ctx     context.Context
+	pattern string
+	prefix  string
+	opts    *ScanOptions
+	nodes   []*redis.Client
+	nodeIdx int
+	iter    *pageIterator
+	err     error
+	wrapErr func(cmd string, err error) error
+	process func(ctx context.Context, node *redis.Client, cmd redis.Cmder) error
+}
+
+// ScanKeys 使用 SCAN 遍历匹配 pattern 的 key。
+// cluster 模式下通过 ForEachMaster 获取所有 master 节点并依次遍历，stub/sentinel 模式下遍历当前节点。
+// SCAN 的语义决定了遍历期间新增或删除的 key 可能被遗漏或重复返回。配置了 KeyPrefix 时只遍历带前缀的 key，返回的 key 不带前缀。
+// ctx 限制整个遍历过程，结束之后 Next 返回 false 并记录 ctx 的错误，Next 的 ctx 用于单次 SCAN。
+func (r *Component) ScanKeys(ctx context.Context, pattern string, opts *ScanOptions) *KeyIterator {
+	it := &KeyIterator{
+		ctx:     ctx,
+		pattern: pattern,
+		prefix:  r.config.KeyPrefix,
+		opts:    opts.withDefaults(),
+	}
+	if it.prefix != "" {
+		if pattern == "" {
+			pattern = "*"
+		}
+		it.pattern = escapeGlob(it.prefix) + pattern
+	}
+	nodes, err := r.masters(ctx)
+	it.nodes, it.err = nodes, r.wrapErr("scan", err)
+	it.wrapErr = r.wrapErr
+	it.process = r.nodeProcess
+	return it
+}
+
+// nodeProcess 在 master 节点的客户端上执行命令。cluster 模式下 ForEachMaster 返回的节点客户端没有组件的拦截器，
+// 按照 AddHook 的顺序套上拦截器，保证 SCAN 同样经过限流、守卫、监控和 trace
+func (r *Component) nodeProcess(ctx context.Context, node *redis.Client, cmd redis.Cmder) error {
+	process := node.Process
+	if r.Cluster() != nil {
+		for i := len(r.config.interceptors) - 1; i >= 0; i-- {
+			process = r.config.interceptors[i].ProcessHook(process)
+		}
+	}
+	return process(ctx, cmd)
+}
+
+// masters 返回所有 master 节点的客户端，按地址排序
+func (r *Component) masters(ctx context.Context) ([]*redis.Client, error) {
+	if c := r.Cluster(); c != nil {
+		var (
+			mu    sync.Mutex
+			nodes = make([]*redis.Client, 0)
+		)
+		err := c.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			mu.Lock()
+			nodes = append(nodes, client)
+			mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].Options().Addr < nodes[j].Options().Addr
+		})
+		return nodes, nil
+	}
+	if c := r.Stub(); c != nil {
+		return []*redis.Client{c}, nil
+	}
+	return nil, ErrInvalidParams
+}
+
+// Next 移动到下一个 key，遍历结束或出错时返回 false
+func (it *KeyIterator) Next(ctx context.Context) bool {
+	for it.err == nil {
+		if it.iter == nil {
+			if it.nodeIdx >= len(it.nodes) {
+				return false
+			}
+			it.iter = it.newNodeIterator(it.nodes[it.nodeIdx])
+		}
+		if it.iter.next(ctx) {
+			return true
+		}
+		if it.iter.err != nil {
+			it.err = it.iter.err
+			return false
+		}
+		it.iter = nil
+		it.nodeIdx++
+	}
+	return false
+}
+
+func (it *KeyIterator) newNodeIterator(node *redis.Client) *pageIterator {
+	pattern, count, keyType := it.pattern, it.opts.Count, it.opts.Type
+	return &pageIterator{
+		stride: 1,
+		scanner: &scanner{
+			base:     it.ctx,
+			interval: it.opts.Interval,
+			scan: func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+				// cluster 模式下节点客户端没有 key 前缀拦截器，统一在这里处理前缀
+				ctx = withoutKeyPrefix(ctx)
+				args := []interface{}{"scan", cursor}
+				if pattern != "" {
+					args = append(args, "match", pattern)
+				}
+				args = append(args, "count", count)
+				if keyType != "" {
+					args = append(args, "type", keyType)
+				}
+				cmd := redis.NewScanCmd(ctx, nil, args...)
+				_ = it.process(ctx, node, cmd)
+				page, cursor := cmd.Val()
+				if it.prefix != "" {
+					for i := range page {
+						page[i] = page[i][len(it.prefix):]
+					}
+				}
+				return page, cursor, it.wrapErr("scan", cmd.Err())
+			},
+		},
+	}
+}
+
+// Key 返回当前的 key
+func (it *KeyIterator) Key() string {
+	if it.iter == nil || len(it.iter.val) == 0 {
+		return ""
+	}
+	return it.iter.val[0]
+}
+
+// Node 返回当前正在遍历的节点地址
+func (it *KeyIterator) Node() string {
+	if it.nodeIdx >= len(it.nodes) {
+		return ""
+	}
+	return it.nodes[it.nodeIdx].Options().Addr
+}
+
+// Err 返回遍历过程中的错误
+func (it *KeyIterator) Err() error {
+	return it.err
+}
+
+// HScanIterator 遍历 hash 的 field/value，由 HScan 创建
+type HScanIterator struct {
+	iter *pageIterator
+}
+
+// HScan 使用 HSCAN 遍历 hash 中匹配 match 的 field，ctx 与 ScanKeys 相同，限制整个遍历过程
+func (r *Component) HScan(ctx context.Context, key string, match string, opts *ScanOptions) *HScanIterator {
+	opts = opts.withDefaults()
+	return &HScanIterator{iter: &pageIterator{
+		stride: 2,
+		scanner: &scanner{
+			base:     ctx,
+			interval: opts.Interval,
+			scan: func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+				cmd := r.client.HScan(ctx, key, cursor, match, opts.Count)
+				page, cursor := cmd.Val()
+				return page, cursor, r.cmdErr(cmd)
+			},
+		},
+	}}
+}
+
+// Next 移动到下一个 field，遍历结束或出错时返回 false
+func (it *HScanIterator) Next(ctx context.Context) bool {
+	return it.iter.next(ctx)
+}
+
+// Field 返回当前的 field
+func (it *HScanIterator) Field() string {
+	return it.iter.val[0]
+}
+
+// Value 返回当前 field 的值
+func (it *HScanIterator) Value() string {
+	return it.iter.val[1]
+}
+
+// Err 返回遍历过程中的错误
+func (it *HScanIterator) Err() error {
+	return it.iter.err
+}
+
+// SScanIterator 遍历 set 的成员，由 SScan 创建
+type SScanIterator struct {
+	iter *pageIterator
+}
+
+// SScan 使用 SSCAN 遍历 set 中匹配 match 的成员，ctx 与 ScanKeys 相同，限制整个遍历过程
+func (r *Component) SScan(ctx context.Context, key string, match string, opts *ScanOptions) *SScanIterator {
+	opts = opts.withDefaults()
+	return &SScanIterator{iter: &pageIterator{
+		stride: 1,
+		scanner: &scanner{
+			base:     ctx,
+			interval: opts.Interval,
+			scan: func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+				cmd := r.client.SScan(ctx, key, cursor, match, opts.Count)
+				page, cursor := cmd.Val()
+				return page, cursor, r.cmdErr(cmd)
+			},
+		},
+	}}
+}
+
+// Next 移动到下一个成员，遍历结束或出错时返回 false
+func (it *SScanIterator) Next(ctx context.Context) bool {
+	return it.iter.next(ctx)
+}
+
+// Member 返回当前的成员
+func (it *SScanIterator) Member() string {
+	return it.iter.val[0]
+}
+
+// Err 返回遍历过程中的错误
+func (it *SScanIterator) Err() error {
+	return it.iter.err
+}
+
+// ZScanIterator 遍历有序集合的成员和分数，由 ZScan 创建
+type ZScanIterator struct {
+	iter *pageIterator
+	z    redis.Z
+}
+
+// ZScan 使用 ZSCAN 遍历有序集合中匹配 match 的成员，ctx 与 ScanKeys 相同，限制整个遍历过程
+func (r *Component) ZScan(ctx context.Context, key string, match string, opts *ScanOptions) *ZScanIterator {
+	opts = opts.withDefaults()
+	return &ZScanIterator{iter: &pageIterator{
+		stride: 2,
+		scanner: &scanner{
+			base:     ctx,
+			interval: opts.Interval,
+			scan: func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+				cmd := r.client.ZScan(ctx, key, cursor, match, opts.Count)
+				page, cursor := cmd.Val()
+				return page, cursor, r.cmdErr(cmd)
+			},
+		},
+	}}
+}
+
+// Next 移动到下一个成员，遍历结束或出错时返回 false
+func (it *ZScanIterator) Next(ctx context.Context) bool {
+	if !it.iter.next(ctx) {
+		return false
+	}
+	score, err := strconv.ParseFloat(it.iter.val[1], 64)
+	if err != nil {
+		it.iter.err = err
+		return false
+	}
+	it.z = redis.Z{Member: it.iter.val[0], Score: score}
+	return true
+}
+
+// Val 返回当前的成员和分数
+func (it *ZScanIterator) Val() redis.Z {
+	return it.z
+}
+
+// Err 返回遍历过程中的错误
+func (it *ZScanIterator) Err() error {
+	return it.iter.err
+}
+
+// DeleteByPattern 遍历所有 master 节点，使用 UNLINK 分批删除匹配 pattern 的 key，返回删除的 key 数量
+func (r *Component) DeleteByPattern(ctx context.Context, pattern string, opts *ScanOptions) (int64, error) {
+	return r.applyByPattern(ctx, pattern, opts, func(ctx context.Context, keys []string) (int64, error) {
+		n, err := r.unlink(ctx, keys)
+		return n, r.wrapErr("unlink", err)
+	})
+}
+
+// ExpireByPattern 遍历所有 master 节点，分批为匹配 pattern 的 key 设置过期时间，返回设置成功的 key 数量
+func (r *Component) ExpireByPattern(ctx context.Context, pattern string, expiration time.Duration, opts *ScanOptions) (int64, error) {
+	return r.applyByPattern(ctx, pattern, opts, func(ctx context.Context, keys []string) (int64, error) {
+		n, err := r.expire(ctx, keys, expiration)
+		return n, r.wrapErr("expire", err)
+	})
+}
+
+func (r *Component) applyByPattern(ctx context.Context, pattern string, opts *ScanOptions, fn func(ctx context.Context, keys []string) (int64, error)) (int64, error) {
+	opts = opts.withDefaults()
+	it := r.ScanKeys(ctx, pattern, opts)
+	progress := ScanProgress{}
+	batch := make([]string, 0, opts.BatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := fn(ctx, batch)
+		progress.Affected += n
+		batch = batch[:0]
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+		return err
+	}
+
+	for it.Next(ctx) {
+		progress.Node = it.Node()
+		progress.Scanned++
+		batch = append(batch, it.Key())
+		if len(batch) >= opts.BatchSize {
+			if err := flush(); err != nil {
+				return progress.Affected, err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return progress.Affected, err
+	}
+	return progress.Affected, flush()
+}
+
+// expire 通过 pipeline 批量设置过期时间，返回设置成功的 key 数量
+func (r *Component) expire(ctx context.Context, keys []string, expiration time.Duration) (int64, error) {
+	cmds := make([]*redis.BoolCmd, len(keys))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Expire(ctx, key, expiration)
+		}
+		return nil
+	})
+
+	var total int64
+	for _, cmd := range cmds {
+		if cmd.Val() {
+			total++
+		}
+	}
+	return total, err
+}
@@ -0,0 +1,142 @@
+package eredis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageIterator(t *testing.T) {
+	pages := map[uint64][]string{
+		0: {"f1", "v1"},
+		7: {},
+		9: {"f2", "v2", "f3", "v3"},
+	}
+	next := map[uint64]uint64{0: 7, 7: 9, 9: 0}
+	it := &pageIterator{
+		stride: 2,
+		scanner: &scanner{
+			interval: 5 * time.Millisecond,
+			scan: func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+				return pages[cursor], next[cursor], nil
+			},
+		},
+	}
+
+	start := time.Now()
+	fields := make([]string, 0)
+	for it.next(context.Background()) {
+		fields = append(fields, it.val[0]+"="+it.val[1])
+	}
+	assert.NoError(t, it.err)
+	assert.Equal(t, []string{"f1=v1", "f2=v2", "f3=v3"}, fields)
+	// 三次 SCAN 之间至少间隔两次 interval
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+}
+
+func TestPageIteratorCanceled(t *testing.T) {
+	it := &pageIterator{
+		stride: 1,
+		scanner: &scanner{
+			interval: time.Hour,
+			scan: func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+				return []string{}, cursor + 1, nil
+			},
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.False(t, it.next(ctx))
+	assert.ErrorIs(t, it.err, context.DeadlineExceeded)
+}
+
+func TestScanCollections(t *testing.T) {
+	comp := newTestRedis(t, "redis.scanTest")
+	ctx := context.Background()
+	opts := &ScanOptions{Count: 2}
+
+	hash := newTestKey(t, comp, "hscan")
+	assert.NoError(t, comp.Client().HSet(ctx, hash, "a:1", "1", "a:2", "2", "b:1", "3").Err())
+	fields := make(map[string]string)
+	hit := comp.HScan(ctx, hash, "a:*", opts)
+	for hit.Next(ctx) {
+		fields[hit.Field()] = hit.Value()
+	}
+	assert.NoError(t, hit.Err())
+	assert.Equal(t, map[string]string{"a:1": "1", "a:2": "2"}, fields)
+
+	set := newTestKey(t, comp, "sscan")
+	assert.NoError(t, comp.Client().SAdd(ctx, set, "x", "y", "z").Err())
+	members := make([]string, 0)
+	sit := comp.SScan(ctx, set, "", opts)
+	for sit.Next(ctx) {
+		members = append(members, sit.Member())
+	}
+	assert.NoError(t, sit.Err())
+	assert.ElementsMatch(t, []string{"x", "y", "z"}, members)
+
+	zset := newTestKey(t, comp, "zscan")
+	assert.NoError(t, comp.Client().ZAdd(ctx, zset, redis.Z{Member: "m1", Score: 1.5}, redis.Z{Member: "m2", Score: -2}).Err())
+	zs := make([]redis.Z, 0)
+	zit := comp.ZScan(ctx, zset, "m*", opts)
+	for zit.Next(ctx) {
+		zs = append(zs, zit.Val())
+	}
+	assert.NoError(t, zit.Err())
+	assert.ElementsMatch(t, []redis.Z{{Member: "m1", Score: 1.5}, {Member: "m2", Score: -2}}, zs)
+
+	// 创建迭代器的 ctx 结束之后 Next 返回 false 并记录错误
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	sit = comp.SScan(canceled, set, "", opts)
+	assert.False(t, sit.Next(ctx))
+	assert.ErrorIs(t, sit.Err(), context.Canceled)
+}
+
+func TestScanKeysCluster(t *testing.T) {
+	recorder := &commandRecorder{}
+	comp := newTestCluster(t, "redis.scanClusterTest", withInterceptor(recorder))
+	ctx := context.Background()
+	prefix := newTestKey(t, comp, "scankeys")
+	keys := []string{prefix + ":a", prefix + ":b", prefix + ":c"}
+	for _, key := range keys {
+		assert.NoError(t, comp.Set(ctx, key, "1", time.Minute))
+	}
+	t.Cleanup(func() { _, _ = comp.Del(ctx, keys...) })
+
+	// cluster 模式下在节点客户端上执行的 SCAN 同样经过组件的拦截器
+	got := make([]string, 0)
+	it := comp.ScanKeys(ctx, prefix+":*", &ScanOptions{Count: 1})
+	for it.Next(ctx) {
+		got = append(got, it.Key())
+	}
+	assert.NoError(t, it.Err())
+	assert.ElementsMatch(t, keys, got)
+	assert.Greater(t, recorder.count("scan"), 0)
+
+	n, err := comp.DeleteByPattern(ctx, prefix+":*", &ScanOptions{BatchSize: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+}
+
+func TestPageIteratorBaseCanceled(t *testing.T) {
+	base, cancel := context.WithCancel(context.Background())
+	it := &pageIterator{
+		stride: 1,
+		scanner: &scanner{
+			base:     base,
+			interval: time.Hour,
+			scan: func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+				return []string{"a"}, cursor + 1, nil
+			},
+		},
+	}
+	assert.True(t, it.next(context.Background()))
+	// 等待 interval 期间创建迭代器的 ctx 结束
+	time.AfterFunc(10*time.Millisecond, cancel)
+	assert.False(t, it.next(context.Background()))
+	assert.ErrorIs(t, it.err, context.Canceled)
+}
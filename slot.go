@@ -0,0 +1,71 @@
+package eredis
+
+import (
+	"strings"
+)
+
+// slotNumber redis cluster 的 slot 总数
+const slotNumber = 16384
+
+// crc16tab CRC16-CCITT(XMODEM) 查找表，与 redis cluster 规范保持一致
+// http://redis.io/topics/cluster-spec#appendix-a-crc16-reference-implementation-in-ansi-c
+var crc16tab = func() (tab [256]uint16) {
+	for i := 0; i < 256; i++ {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		tab[i] = crc
+	}
+	return
+}()
+
+func crc16sum(key string) (crc uint16) {
+	for i := 0; i < len(key); i++ {
+		crc = (crc << 8) ^ crc16tab[(byte(crc>>8)^key[i])&0x00ff]
+	}
+	return crc
+}
+
+// hashTag 返回 key 中参与 slot 计算的部分，存在 {tag} 时只取 tag
+func hashTag(key string) string {
+	if s := strings.IndexByte(key, '{'); s > -1 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			return key[s+1 : s+e+1]
+		}
+	}
+	return key
+}
+
+// Slot 计算 key 在 redis cluster 中所属的 slot
+func Slot(key string) int {
+	return int(crc16sum(hashTag(key))) % slotNumber
+}
+
+// slotGroup 同一个 slot 下的一组 key，idx 记录 key 在原始入参中的位置
+type slotGroup struct {
+	keys []string
+	idx  []int
+}
+
+// groupKeysBySlot 按加上 prefix 之后的 slot 对 key 分组，分组顺序与 key 首次出现的顺序一致
+func groupKeysBySlot(prefix string, keys []string) []*slotGroup {
+	groups := make([]*slotGroup, 0)
+	bySlot := make(map[int]*slotGroup)
+	for i, key := range keys {
+		slot := Slot(prefix + key)
+		group, ok := bySlot[slot]
+		if !ok {
+			group = &slotGroup{}
+			bySlot[slot] = group
+			groups = append(groups, group)
+		}
+		group.keys = append(group.keys, key)
+		group.idx = append(group.idx, i)
+	}
+	return groups
+}
@@ -0,0 +1,29 @@
+package eredis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlot(t *testing.T) {
+	// 参考 redis cluster 规范中的 CRC16 校验值
+	assert.Equal(t, uint16(0x31c3), crc16sum("123456789"))
+	assert.Equal(t, 12182, Slot("foo"))
+	assert.Equal(t, Slot("{user1000}.following"), Slot("{user1000}.followers"))
+	assert.Equal(t, Slot("user1000"), Slot("{user1000}.following"))
+	// 空 tag 时使用完整的 key
+	assert.Equal(t, Slot("foo{}{bar}"), int(crc16sum("foo{}{bar}"))%slotNumber)
+}
+
+func TestGroupKeysBySlot(t *testing.T) {
+	keys := []string{"{a}1", "{b}1", "{a}2", "{c}1", "{b}2"}
+	groups := groupKeysBySlot("", keys)
+	assert.Len(t, groups, 3)
+	assert.Equal(t, []string{"{a}1", "{a}2"}, groups[0].keys)
+	assert.Equal(t, []int{0, 2}, groups[0].idx)
+	assert.Equal(t, []string{"{b}1", "{b}2"}, groups[1].keys)
+	assert.Equal(t, []int{1, 4}, groups[1].idx)
+	assert.Equal(t, []string{"{c}1"}, groups[2].keys)
+	assert.Equal(t, []int{3}, groups[2].idx)
+}
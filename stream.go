@@ -0,0 +1,483 @@
+package eredis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/gotomicro/ego/server"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 写入死信队列时附加的字段
+const (
+	StreamFieldSource     = "eredis_source"
+	StreamFieldSourceID   = "eredis_source_id"
+	StreamFieldDeliveries = "eredis_deliveries"
+)
+
+var (
+	streamHandleCounter = emetric.CounterVecOpts{
+		Namespace: emetric.DefaultNamespace,
+		Name:      "client_redis_stream_handle_total",
+		Help:      "redis stream messages handled by consumer groups",
+		Labels:    []string{"name", "stream", "group", "result"},
+	}.Build()
+
+	streamHandleHistogram = emetric.HistogramVecOpts{
+		Namespace: emetric.DefaultNamespace,
+		Name:      "client_redis_stream_handle_seconds",
+		Help:      "redis stream message handle duration",
+		Labels:    []string{"name", "stream", "group"},
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}.Build()
+
+	streamLagGauge = emetric.GaugeVecOpts{
+		Namespace: emetric.DefaultNamespace,
+		Name:      "client_redis_stream_lag",
+		Help:      "entries not yet delivered to the consumer group, requires redis 7.0",
+		Labels:    []string{"name", "stream", "group"},
+	}.Build()
+
+	streamPendingGauge = emetric.GaugeVecOpts{
+		Namespace: emetric.DefaultNamespace,
+		Name:      "client_redis_stream_pending",
+		Help:      "entries delivered to the consumer group but not yet acknowledged",
+		Labels:    []string{"name", "stream", "group"},
+	}.Build()
+)
+
+// StreamProducerOption stream 生产者选项
+type StreamProducerOption func(p *StreamProducer)
+
+// WithStreamMaxLen 写入时按 MAXLEN 裁剪 stream
+func WithStreamMaxLen(maxLen int64) StreamProducerOption {
+	return func(p *StreamProducer) {
+		p.maxLen = maxLen
+	}
+}
+
+// WithStreamMaxAge 写入时按 MINID 裁剪 stream，删除早于 maxAge 的消息，只对自动生成的 ID 有意义
+func WithStreamMaxAge(maxAge time.Duration) StreamProducerOption {
+	return func(p *StreamProducer) {
+		p.maxAge = maxAge
+	}
+}
+
+// WithStreamExactTrim 精确裁剪，默认使用 ~ 近似裁剪，性能更好
+func WithStreamExactTrim() StreamProducerOption {
+	return func(p *StreamProducer) {
+		p.approx = false
+	}
+}
+
+// StreamProducer stream 生产者，由 NewStreamProducer 创建
+type StreamProducer struct {
+	comp   *Component
+	stream string
+	maxLen int64
+	maxAge time.Duration
+	approx bool
+}
+
+// NewStreamProducer 创建 stream 生产者
+func (r *Component) NewStreamProducer(stream string, opts ...StreamProducerOption) *StreamProducer {
+	p := &StreamProducer{comp: r, stream: stream, approx: true}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Add 使用 XADD 写入消息，返回消息 ID。开启 EnableTracePropagation 时在 traceparent、tracestate 字段中写入 trace context
+func (p *StreamProducer) Add(ctx context.Context, values map[string]interface{}) (id string, err error) {
+	if p.comp.config != nil && p.comp.config.EnableTracePropagation {
+		var (
+			span    trace.Span
+			carrier propagation.MapCarrier
+		)
+		ctx, span, carrier = startProducerSpan(ctx, "XADD "+p.stream, p.stream)
+		defer func() { endSpan(span, err) }()
+		traced := make(map[string]interface{}, len(values)+len(carrier))
+		for k, v := range values {
+			traced[k] = v
+		}
+		for k, v := range carrier {
+			traced[k] = v
+		}
+		values = traced
+	}
+	args := &redis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: p.maxLen,
+		Approx: p.approx,
+		Values: values,
+	}
+	if p.maxAge > 0 {
+		args.MinID = fmt.Sprintf("%d-0", time.Now().Add(-p.maxAge).UnixMilli())
+	}
+	cmd := p.comp.client.XAdd(ctx, args)
+	return cmd.Val(), p.comp.cmdErr(cmd)
+}
+
+// StreamMessage 消费到的 stream 消息
+type StreamMessage struct {
+	Stream     string
+	ID         string
+	Values     map[string]interface{}
+	Deliveries int64 // Deliveries 投递次数，首次投递为 1，重新认领之后递增
+}
+
+// StreamHandler 处理 stream 消息，返回 nil 时自动 XACK，返回错误时消息留在 pending 列表中等待重新认领
+type StreamHandler func(ctx context.Context, msg *StreamMessage) error
+
+// StreamConsumerOption stream 消费者选项
+type StreamConsumerOption func(c *StreamConsumer)
+
+// WithStreamWorkers 并发消费的 worker 数量，默认 1
+func WithStreamWorkers(workers int) StreamConsumerOption {
+	return func(c *StreamConsumer) {
+		c.workers = workers
+	}
+}
+
+// WithStreamConsumerName 消费者名称，默认为 hostname-pid，同一个 group 中的多个实例必须不同
+func WithStreamConsumerName(name string) StreamConsumerOption {
+	return func(c *StreamConsumer) {
+		c.consumer = name
+	}
+}
+
+// WithStreamBatchSize 每次 XREADGROUP、XAUTOCLAIM 读取的消息数量，默认 10
+func WithStreamBatchSize(size int64) StreamConsumerOption {
+	return func(c *StreamConsumer) {
+		c.batchSize = size
+	}
+}
+
+// WithStreamBlock XREADGROUP 的阻塞时间，也是优雅退出时最长的等待时间，默认 2s
+func WithStreamBlock(block time.Duration) StreamConsumerOption {
+	return func(c *StreamConsumer) {
+		c.block = block
+	}
+}
+
+// WithStreamClaim 消息在 pending 列表中超过 minIdle 之后通过 XAUTOCLAIM 重新认领，每 interval 检查一次，默认 1min、30s
+func WithStreamClaim(minIdle, interval time.Duration) StreamConsumerOption {
+	return func(c *StreamConsumer) {
+		c.claimIdle = minIdle
+		c.claimInterval = interval
+	}
+}
+
+// WithStreamMaxRetries 最大重试次数，超过之后写入死信 stream，默认 5，小于 0 表示不限制
+func WithStreamMaxRetries(retries int64) StreamConsumerOption {
+	return func(c *StreamConsumer) {
+		c.maxRetries = retries
+	}
+}
+
+// WithStreamDeadLetter 死信 stream，默认为 stream + ":dlq"
+func WithStreamDeadLetter(stream string) StreamConsumerOption {
+	return func(c *StreamConsumer) {
+		c.deadLetter = stream
+	}
+}
+
+// WithStreamStartID 创建 group 时的起始 ID，默认为 $，只消费之后写入的消息，0 表示从头消费
+func WithStreamStartID(id string) StreamConsumerOption {
+	return func(c *StreamConsumer) {
+		c.startID = id
+	}
+}
+
+var _ server.Server = (*StreamConsumer)(nil)
+
+// StreamConsumer stream 消费者组，由 NewStreamConsumer 创建。
+// 实现了 ego 的 server.Server 接口，可以通过 ego.New().Serve(consumer) 启动，随应用优雅退出。
+type StreamConsumer struct {
+	comp          *Component
+	stream        string
+	group         string
+	consumer      string
+	handler       StreamHandler
+	workers       int
+	batchSize     int64
+	block         time.Duration
+	claimIdle     time.Duration
+	claimInterval time.Duration
+	maxRetries    int64
+	deadLetter    string
+	startID       string
+	logger        *elog.Component
+
+	consumerServer
+	workerGroup
+}
+
+// NewStreamConsumer 创建 stream 消费者组
+func (r *Component) NewStreamConsumer(stream, group string, handler StreamHandler, opts ...StreamConsumerOption) *StreamConsumer {
+	hostname, _ := os.Hostname()
+	c := &StreamConsumer{
+		comp:           r,
+		stream:         stream,
+		group:          group,
+		consumer:       fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		handler:        handler,
+		workers:        1,
+		batchSize:      10,
+		block:          2 * time.Second,
+		claimIdle:      time.Minute,
+		claimInterval:  30 * time.Second,
+		maxRetries:     5,
+		deadLetter:     stream + ":dlq",
+		startID:        "$",
+		consumerServer: consumerServer{name: r.name, scheme: "redis-stream", address: stream + "/" + group},
+		workerGroup:    newWorkerGroup(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.workers <= 0 {
+		c.workers = 1
+	}
+	if c.claimInterval <= 0 {
+		c.claimInterval = 30 * time.Second
+	}
+	c.logger = r.logger.With(elog.String("stream", stream), elog.String("group", group), elog.String("consumer", c.consumer))
+	return c
+}
+
+// Start 创建消费者组并启动 worker，阻塞直到停止
+func (c *StreamConsumer) Start() error {
+	if err := c.createGroup(c.ctx); err != nil {
+		return err
+	}
+	for i := 0; i < c.workers; i++ {
+		c.run(c.work)
+	}
+	c.run(c.reclaim)
+	c.wg.Wait()
+	return nil
+}
+
+// Stop 立即停止，正在处理的消息的 context 被取消
+func (c *StreamConsumer) Stop() error {
+	c.stop()
+	return nil
+}
+
+// GracefulStop 停止拉取新消息，等待正在处理的消息完成，ctx 结束时取消正在处理的消息
+func (c *StreamConsumer) GracefulStop(ctx context.Context) error {
+	c.gracefulStop(ctx)
+	return nil
+}
+
+// createGroup 创建消费者组，stream 不存在时自动创建，group 已经存在时忽略
+func (c *StreamConsumer) createGroup(ctx context.Context) error {
+	err := c.comp.client.XGroupCreateMkStream(ctx, c.stream, c.group, c.startID).Err()
+	if err != nil && !redis.HasErrorPrefix(err, "BUSYGROUP") {
+		return c.comp.wrapErr("xgroup", err)
+	}
+	return nil
+}
+
+// work 通过 XREADGROUP 拉取新消息并处理
+func (c *StreamConsumer) work() {
+	for !c.stopped() {
+		streams, err := c.comp.client.XReadGroup(c.ctx, &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.consumer,
+			Streams:  []string{c.stream, ">"},
+			Count:    c.batchSize,
+			Block:    c.block,
+		}).Result()
+		if err != nil {
+			if IsNil(err) || c.stopped() {
+				continue
+			}
+			c.logger.Error("xreadgroup fail", elog.FieldName(c.comp.name), elog.FieldErr(err))
+			// stream 被删除之后重新创建消费者组
+			if redis.HasErrorPrefix(err, "NOGROUP") {
+				_ = c.createGroup(c.ctx)
+			}
+			_ = c.sleep(time.Second)
+			continue
+		}
+		for _, s := range streams {
+			for _, msg := range s.Messages {
+				c.handle(msg, 1)
+			}
+		}
+	}
+}
+
+// handle 处理一条消息，成功之后 XACK。消息中带有 trace context 时创建 consumer span
+func (c *StreamConsumer) handle(msg redis.XMessage, deliveries int64) {
+	start := time.Now()
+	ctx := c.ctx
+	carrier := extractStreamTrace(msg.Values)
+	var span trace.Span
+	if carrier != nil {
+		ctx, span = startConsumerSpan(ctx, "XREADGROUP "+c.stream, c.stream, carrier,
+			semconv.MessagingMessageIDKey.String(msg.ID),
+			attribute.String("messaging.redis.group", c.group),
+			attribute.Int64("messaging.redis.deliveries", deliveries),
+		)
+	}
+	err := callHandler("stream", func() error {
+		return c.handler(ctx, &StreamMessage{Stream: c.stream, ID: msg.ID, Values: msg.Values, Deliveries: deliveries})
+	})
+	if span != nil {
+		endSpan(span, err)
+	}
+	streamHandleHistogram.Observe(time.Since(start).Seconds(), c.comp.name, c.stream, c.group)
+	if err != nil {
+		streamHandleCounter.Inc(c.comp.name, c.stream, c.group, "error")
+		c.logger.Error("handle stream message fail", elog.FieldName(c.comp.name), elog.String("id", msg.ID), elog.Int64("deliveries", deliveries), elog.FieldErr(err))
+		return
+	}
+	streamHandleCounter.Inc(c.comp.name, c.stream, c.group, "ok")
+	if err := c.comp.client.XAck(c.ctx, c.stream, c.group, msg.ID).Err(); err != nil {
+		c.logger.Error("xack fail", elog.FieldName(c.comp.name), elog.String("id", msg.ID), elog.FieldErr(err))
+	}
+}
+
+// reclaim 定期认领超时的消息，并上报消费延迟
+func (c *StreamConsumer) reclaim() {
+	ticker := time.NewTicker(c.claimInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopping:
+			return
+		case <-ticker.C:
+		}
+		c.claim()
+		c.reportLag()
+	}
+}
+
+// claim 通过 XAUTOCLAIM 认领在 pending 列表中超过 claimIdle 的消息，超过最大重试次数的消息写入死信 stream
+func (c *StreamConsumer) claim() {
+	start := "0-0"
+	for !c.stopped() {
+		msgs, next, err := c.comp.client.XAutoClaim(c.ctx, &redis.XAutoClaimArgs{
+			Stream:   c.stream,
+			Group:    c.group,
+			MinIdle:  c.claimIdle,
+			Start:    start,
+			Count:    c.batchSize,
+			Consumer: c.consumer,
+		}).Result()
+		if err != nil {
+			c.logger.Error("xautoclaim fail", elog.FieldName(c.comp.name), elog.FieldErr(err))
+			return
+		}
+		c.handleClaimed(msgs)
+		if next == "" || next == "0-0" {
+			return
+		}
+		start = next
+	}
+}
+
+func (c *StreamConsumer) handleClaimed(msgs []redis.XMessage) {
+	if len(msgs) == 0 {
+		return
+	}
+	deliveries, err := c.deliveries(msgs)
+	if err != nil {
+		c.logger.Error("xpending fail", elog.FieldName(c.comp.name), elog.FieldErr(err))
+		return
+	}
+	for _, msg := range msgs {
+		if c.stopped() {
+			return
+		}
+		// 消息已经被删除
+		if msg.Values == nil {
+			_ = c.comp.client.XAck(c.ctx, c.stream, c.group, msg.ID).Err()
+			continue
+		}
+		n := deliveries[msg.ID]
+		if c.maxRetries >= 0 && n-1 > c.maxRetries {
+			c.moveToDeadLetter(msg, n)
+			continue
+		}
+		c.handle(msg, n)
+	}
+}
+
+// deliveries 通过 XPENDING 查询消息的投递次数
+func (c *StreamConsumer) deliveries(msgs []redis.XMessage) (map[string]int64, error) {
+	cmds := make([]*redis.XPendingExtCmd, len(msgs))
+	_, err := c.comp.client.Pipelined(c.ctx, func(pipe redis.Pipeliner) error {
+		for i, msg := range msgs {
+			cmds[i] = pipe.XPendingExt(c.ctx, &redis.XPendingExtArgs{
+				Stream:   c.stream,
+				Group:    c.group,
+				Start:    msg.ID,
+				End:      msg.ID,
+				Count:    1,
+				Consumer: c.consumer,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, c.comp.wrapErr("xpending", err)
+	}
+	ret := make(map[string]int64, len(msgs))
+	for _, cmd := range cmds {
+		for _, p := range cmd.Val() {
+			ret[p.ID] = p.RetryCount
+		}
+	}
+	return ret, nil
+}
+
+// moveToDeadLetter 写入死信 stream 之后 XACK，写入失败时消息留在 pending 列表中
+func (c *StreamConsumer) moveToDeadLetter(msg redis.XMessage, deliveries int64) {
+	values := make(map[string]interface{}, len(msg.Values)+3)
+	for k, v := range msg.Values {
+		values[k] = v
+	}
+	values[StreamFieldSource] = c.stream
+	values[StreamFieldSourceID] = msg.ID
+	values[StreamFieldDeliveries] = deliveries
+	if err := c.comp.client.XAdd(c.ctx, &redis.XAddArgs{Stream: c.deadLetter, Values: values}).Err(); err != nil {
+		c.logger.Error("move to dead letter fail", elog.FieldName(c.comp.name), elog.String("id", msg.ID), elog.FieldErr(err))
+		return
+	}
+	streamHandleCounter.Inc(c.comp.name, c.stream, c.group, "dead_letter")
+	c.logger.Warn("move to dead letter", elog.FieldName(c.comp.name), elog.String("id", msg.ID), elog.Int64("deliveries", deliveries), elog.String("deadLetter", c.deadLetter))
+	if err := c.comp.client.XAck(c.ctx, c.stream, c.group, msg.ID).Err(); err != nil {
+		c.logger.Error("xack fail", elog.FieldName(c.comp.name), elog.String("id", msg.ID), elog.FieldErr(err))
+	}
+}
+
+// reportLag 通过 XINFO GROUPS 上报未投递和未确认的消息数量
+func (c *StreamConsumer) reportLag() {
+	groups, err := c.comp.client.XInfoGroups(c.ctx, c.stream).Result()
+	if err != nil {
+		c.logger.Error("xinfo groups fail", elog.FieldName(c.comp.name), elog.FieldErr(err))
+		return
+	}
+	for _, g := range groups {
+		if g.Name != c.group {
+			continue
+		}
+		streamPendingGauge.Set(float64(g.Pending), c.comp.name, c.stream, c.group)
+		if g.Lag >= 0 {
+			streamLagGauge.Set(float64(g.Lag), c.comp.name, c.stream, c.group)
+		}
+	}
+}
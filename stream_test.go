@@ -0,0 +1,110 @@
+package eredis
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamConsumer(t *testing.T) {
+	comp := newTestRedis(t, "redis.streamTest")
+	ctx := context.Background()
+	stream := newTestKey(t, comp, "stream")
+	deadLetter := stream + ":dlq"
+	t.Cleanup(func() { _, _ = comp.Del(ctx, deadLetter) })
+
+	producer := comp.NewStreamProducer(stream)
+	goodID, err := producer.Add(ctx, map[string]interface{}{"kind": "good"})
+	assert.NoError(t, err)
+	badID, err := producer.Add(ctx, map[string]interface{}{"kind": "bad"})
+	assert.NoError(t, err)
+
+	var (
+		mu         sync.Mutex
+		deliveries = make(map[string][]int64)
+	)
+	consumer := comp.NewStreamConsumer(stream, "billing", func(ctx context.Context, msg *StreamMessage) error {
+		mu.Lock()
+		deliveries[msg.ID] = append(deliveries[msg.ID], msg.Deliveries)
+		mu.Unlock()
+		if msg.Values["kind"] == "bad" {
+			panic("boom")
+		}
+		return nil
+	},
+		WithStreamStartID("0"),
+		WithStreamBlock(50*time.Millisecond),
+		WithStreamClaim(50*time.Millisecond, 50*time.Millisecond),
+		WithStreamMaxRetries(1),
+	)
+	go func() { _ = consumer.Start() }()
+	defer consumer.Stop()
+
+	// 失败的消息通过 XAUTOCLAIM 重试，超过最大重试次数之后写入死信 stream 并 XACK
+	assert.Eventually(t, func() bool {
+		n, err := comp.Client().XLen(ctx, deadLetter).Result()
+		return err == nil && n == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	dead, err := comp.Client().XRange(ctx, deadLetter, "-", "+").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, "bad", dead[0].Values["kind"])
+	assert.Equal(t, stream, dead[0].Values[StreamFieldSource])
+	assert.Equal(t, badID, dead[0].Values[StreamFieldSourceID])
+	assert.Equal(t, "3", dead[0].Values[StreamFieldDeliveries])
+
+	pending, err := comp.Client().XPending(ctx, stream, "billing").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), pending.Count)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int64{1}, deliveries[goodID])
+	assert.Equal(t, []int64{1, 2}, deliveries[badID])
+}
+
+func TestStreamConsumerRestart(t *testing.T) {
+	comp := newTestRedis(t, "redis.streamTest")
+	ctx := context.Background()
+	stream := newTestKey(t, comp, "stream")
+	producer := comp.NewStreamProducer(stream)
+
+	received := make(chan string, 10)
+	newConsumer := func() *StreamConsumer {
+		return comp.NewStreamConsumer(stream, "billing", func(ctx context.Context, msg *StreamMessage) error {
+			received <- msg.Values["kind"].(string)
+			return nil
+		}, WithStreamStartID("0"), WithStreamBlock(50*time.Millisecond))
+	}
+
+	// 消费者组已经存在时重新启动不会返回 BUSYGROUP 错误
+	for _, kind := range []string{"first", "second"} {
+		consumer := newConsumer()
+		errs := make(chan error, 1)
+		go func() { errs <- consumer.Start() }()
+		_, err := producer.Add(ctx, map[string]interface{}{"kind": kind})
+		assert.NoError(t, err)
+		assert.Equal(t, kind, waitMessage(t, received))
+		assert.NoError(t, consumer.GracefulStop(ctx))
+		assert.NoError(t, <-errs)
+	}
+
+	// stream 被删除之后，XREADGROUP 返回 NOGROUP 时重新创建消费者组
+	consumer := newConsumer()
+	go func() { _ = consumer.Start() }()
+	defer consumer.Stop()
+	time.Sleep(100 * time.Millisecond)
+	_, err := comp.Del(ctx, stream)
+	assert.NoError(t, err)
+	_, err = producer.Add(ctx, map[string]interface{}{"kind": "recreated"})
+	assert.NoError(t, err)
+	select {
+	case kind := <-received:
+		assert.Equal(t, "recreated", kind)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for message after the group was recreated")
+	}
+}
@@ -0,0 +1,317 @@
+package eredis
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/gotomicro/ego/core/util/xtime"
+	"github.com/redis/go-redis/v9"
+)
+
+// 限流模式
+const (
+	// ThrottleModeBlock 令牌不足时阻塞等待，等待时间超过 MaxWait 或 ctx 截止时间时返回 ErrThrottled
+	ThrottleModeBlock = "block"
+	// ThrottleModeReject 令牌不足时直接返回 ErrThrottled
+	ThrottleModeReject = "reject"
+)
+
+// 命令类别
+const (
+	CommandClassRead   = "read"
+	CommandClassWrite  = "write"
+	CommandClassScan   = "scan"
+	CommandClassScript = "script"
+)
+
+var throttleCounter = emetric.CounterVecOpts{
+	Namespace: emetric.DefaultNamespace,
+	Name:      "client_redis_throttle_total",
+	Help:      "redis commands throttled by client side rate limiter",
+	Labels:    []string{"name", "class", "result"},
+}.Build()
+
+// ThrottleConfig 客户端命令限流配置，用于防止批处理任务打满 redis
+type ThrottleConfig struct {
+	Rate    float64       // Rate 每秒允许的命令数，0 表示不限制
+	Burst   int           // Burst 突发容量，默认与 Rate 相同
+	Mode    string        // Mode 限流模式 block|reject，默认 block
+	MaxWait time.Duration // MaxWait block 模式下单个命令的最长等待时间，默认 100ms
+	Read    ThrottleRule  // Read 读命令单独的限流规则，Rate 为 0 时使用组件级别的规则
+	Write   ThrottleRule  // Write 写命令单独的限流规则
+	Scan    ThrottleRule  // Scan SCAN、KEYS 类命令单独的限流规则
+	Script  ThrottleRule  // Script EVAL、FCALL 类命令单独的限流规则
+}
+
+// ThrottleRule 按命令类别覆盖的限流规则
+type ThrottleRule struct {
+	Rate  float64 // Rate 每秒允许的命令数，0 表示使用组件级别的规则
+	Burst int     // Burst 突发容量，默认与 Rate 相同
+}
+
+// DefaultThrottleConfig 默认限流配置
+func DefaultThrottleConfig() ThrottleConfig {
+	return ThrottleConfig{
+		Mode:    ThrottleModeBlock,
+		MaxWait: xtime.Duration("100ms"),
+	}
+}
+
+// Priority 请求优先级，通过 WithPriority 写入 context
+type Priority int
+
+const (
+	// PriorityNormal 默认优先级，受限流控制
+	PriorityNormal Priority = iota
+	// PriorityHigh 高优先级，跳过客户端限流，适用于在线请求
+	PriorityHigh
+)
+
+type priorityCtxKey struct{}
+
+// WithPriority 设置请求优先级，高优先级的请求不受客户端限流控制
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityCtxKey{}, p)
+}
+
+func priorityFromContext(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityCtxKey{}).(Priority)
+	return p
+}
+
+// tokenBucket 令牌桶
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// reserve 预留 n 个令牌，返回需要等待的时间；等待时间超过 maxWait 时不预留，ok 为 false。
+// n 超过 burst 时按 burst 计算，否则大的 pipeline 永远无法获取令牌
+func (b *tokenBucket) reserve(n int, maxWait time.Duration) (wait time.Duration, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+
+	tokens := b.tokens - b.cost(n)
+	if tokens < 0 {
+		wait = time.Duration(-tokens / b.rate * float64(time.Second))
+	}
+	if wait > maxWait {
+		return wait, false
+	}
+	b.tokens = tokens
+	return wait, true
+}
+
+// refund 退还 reserve 预留的 n 个令牌
+func (b *tokenBucket) refund(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+b.cost(n))
+}
+
+func (b *tokenBucket) cost(n int) float64 {
+	return math.Min(float64(n), b.burst)
+}
+
+// throttler 按命令类别选择令牌桶
+type throttler struct {
+	name    string
+	config  ThrottleConfig
+	def     *tokenBucket
+	classes map[string]*tokenBucket
+}
+
+func newThrottler(name string, config ThrottleConfig) *throttler {
+	t := &throttler{
+		name:    name,
+		config:  config,
+		classes: make(map[string]*tokenBucket),
+	}
+	if config.Rate > 0 {
+		t.def = newTokenBucket(config.Rate, config.Burst)
+	}
+	for class, rule := range map[string]ThrottleRule{
+		CommandClassRead:   config.Read,
+		CommandClassWrite:  config.Write,
+		CommandClassScan:   config.Scan,
+		CommandClassScript: config.Script,
+	} {
+		if rule.Rate > 0 {
+			t.classes[class] = newTokenBucket(rule.Rate, rule.Burst)
+		}
+	}
+	return t
+}
+
+func (t *throttler) bucket(class string) *tokenBucket {
+	if b, ok := t.classes[class]; ok {
+		return b
+	}
+	return t.def
+}
+
+// throttleDemand class 类别的 n 个命令
+type throttleDemand struct {
+	class string
+	n     int
+}
+
+type throttleReservation struct {
+	bucket *tokenBucket
+	n      int
+}
+
+// wait 为各类别的命令获取令牌，任一类别被拒绝或者等待期间 ctx 结束时退还已经预留的令牌
+func (t *throttler) wait(ctx context.Context, demands ...throttleDemand) error {
+	maxWait := time.Duration(0)
+	if t.config.Mode != ThrottleModeReject {
+		maxWait = t.config.MaxWait
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < maxWait {
+			maxWait = time.Until(deadline)
+		}
+	}
+
+	reserved := make([]throttleReservation, 0, len(demands))
+	refund := func() {
+		for _, r := range reserved {
+			r.bucket.refund(r.n)
+		}
+	}
+	var longest time.Duration
+	for _, d := range demands {
+		b := t.bucket(d.class)
+		if b == nil {
+			continue
+		}
+		wait, ok := b.reserve(d.n, maxWait)
+		if !ok {
+			refund()
+			throttleCounter.Inc(t.name, d.class, "rejected")
+			return ErrThrottled
+		}
+		reserved = append(reserved, throttleReservation{bucket: b, n: d.n})
+		if wait > 0 {
+			throttleCounter.Inc(t.name, d.class, "delayed")
+		}
+		if wait > longest {
+			longest = wait
+		}
+	}
+	if longest <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(longest)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		refund()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// throttleInterceptor 客户端限流拦截器，高优先级请求跳过限流
+func throttleInterceptor(compName string, config *config, logger *elog.Component) *interceptor {
+	t := newThrottler(compName, config.Throttle)
+
+	return newInterceptor(compName, config, logger).
+		setBeforeProcess(func(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+			if priorityFromContext(ctx) == PriorityHigh {
+				return ctx, nil
+			}
+			if err := t.wait(ctx, throttleDemand{class: commandClass(cmd.Name()), n: 1}); err != nil {
+				cmd.SetErr(err)
+				return ctx, err
+			}
+			return ctx, nil
+		}).
+		setBeforeProcessPipeline(func(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+			if priorityFromContext(ctx) == PriorityHigh {
+				return ctx, nil
+			}
+			counts := make(map[string]int)
+			for _, cmd := range cmds {
+				counts[commandClass(cmd.Name())]++
+			}
+			demands := make([]throttleDemand, 0, len(counts))
+			for class, n := range counts {
+				demands = append(demands, throttleDemand{class: class, n: n})
+			}
+			if err := t.wait(ctx, demands...); err != nil {
+				for _, cmd := range cmds {
+					cmd.SetErr(err)
+				}
+				return ctx, err
+			}
+			return ctx, nil
+		})
+}
+
+var (
+	scanCommands = map[string]struct{}{
+		"scan": {}, "hscan": {}, "sscan": {}, "zscan": {}, "keys": {},
+	}
+	scriptCommands = map[string]struct{}{
+		"eval": {}, "evalsha": {}, "eval_ro": {}, "evalsha_ro": {}, "fcall": {}, "fcall_ro": {}, "script": {}, "function": {},
+	}
+	writeCommands = map[string]struct{}{
+		"set": {}, "setex": {}, "psetex": {}, "setnx": {}, "setrange": {}, "getset": {}, "getdel": {}, "getex": {},
+		"mset": {}, "msetnx": {}, "append": {}, "incr": {}, "incrby": {}, "incrbyfloat": {}, "decr": {}, "decrby": {},
+		"del": {}, "unlink": {}, "expire": {}, "expireat": {}, "pexpire": {}, "pexpireat": {}, "persist": {},
+		"rename": {}, "renamenx": {}, "copy": {}, "move": {}, "restore": {}, "setbit": {}, "bitop": {}, "bitfield": {},
+		"hset": {}, "hsetnx": {}, "hmset": {}, "hdel": {}, "hincrby": {}, "hincrbyfloat": {},
+		"lpush": {}, "lpushx": {}, "rpush": {}, "rpushx": {}, "lpop": {}, "rpop": {}, "lset": {}, "lrem": {}, "ltrim": {},
+		"linsert": {}, "lmove": {}, "blmove": {}, "rpoplpush": {}, "brpoplpush": {}, "blpop": {}, "brpop": {}, "lmpop": {}, "blmpop": {},
+		"sadd": {}, "srem": {}, "spop": {}, "smove": {}, "sinterstore": {}, "sunionstore": {}, "sdiffstore": {},
+		"zadd": {}, "zincrby": {}, "zrem": {}, "zremrangebyrank": {}, "zremrangebyscore": {}, "zremrangebylex": {},
+		"zpopmin": {}, "zpopmax": {}, "bzpopmin": {}, "bzpopmax": {}, "zmpop": {}, "bzmpop": {},
+		"zunionstore": {}, "zinterstore": {}, "zdiffstore": {}, "zrangestore": {},
+		"geoadd": {}, "geosearchstore": {}, "pfadd": {}, "pfmerge": {},
+		"xadd": {}, "xdel": {}, "xtrim": {}, "xack": {}, "xclaim": {}, "xautoclaim": {}, "xgroup": {},
+		"publish": {}, "spublish": {}, "flushdb": {}, "flushall": {},
+	}
+)
+
+// commandClass 返回命令所属的类别，未知命令按读命令处理
+func commandClass(name string) string {
+	name = strings.ToLower(name)
+	if _, ok := scanCommands[name]; ok {
+		return CommandClassScan
+	}
+	if _, ok := scriptCommands[name]; ok {
+		return CommandClassScript
+	}
+	if _, ok := writeCommands[name]; ok {
+		return CommandClassWrite
+	}
+	return CommandClassRead
+}
@@ -0,0 +1,101 @@
+package eredis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(10, 2)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		wait, ok := b.reserve(1, 0)
+		assert.True(t, ok)
+		assert.Zero(t, wait)
+	}
+	_, ok := b.reserve(1, 0)
+	assert.False(t, ok)
+
+	wait, ok := b.reserve(1, time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, 100*time.Millisecond, wait)
+
+	// 令牌按速率恢复，且不超过 burst
+	now = now.Add(time.Second)
+	for i := 0; i < 2; i++ {
+		_, ok := b.reserve(1, 0)
+		assert.True(t, ok)
+	}
+	_, ok = b.reserve(1, 0)
+	assert.False(t, ok)
+}
+
+func TestThrottler(t *testing.T) {
+	config := DefaultThrottleConfig()
+	config.Mode = ThrottleModeReject
+	config.Rate = 1
+	config.Scan = ThrottleRule{Rate: 1000}
+	th := newThrottler("test", config)
+
+	ctx := context.Background()
+	assert.NoError(t, th.wait(ctx, throttleDemand{CommandClassRead, 1}))
+	assert.ErrorIs(t, th.wait(ctx, throttleDemand{CommandClassWrite, 1}), ErrThrottled)
+	// scan 类命令使用单独的令牌桶
+	assert.NoError(t, th.wait(ctx, throttleDemand{CommandClassScan, 10}))
+}
+
+func TestThrottlerPipeline(t *testing.T) {
+	config := DefaultThrottleConfig()
+	config.Mode = ThrottleModeReject
+	config.Rate = 1
+	config.Burst = 10
+	config.Write = ThrottleRule{Rate: 1, Burst: 1}
+	th := newThrottler("test", config)
+	ctx := context.Background()
+
+	// 超过 burst 的 pipeline 按 burst 计算
+	assert.NoError(t, th.wait(ctx, throttleDemand{CommandClassRead, 100}))
+	assert.ErrorIs(t, th.wait(ctx, throttleDemand{CommandClassRead, 1}), ErrThrottled)
+
+	// 后面的类别被拒绝时退还前面类别预留的令牌
+	th = newThrottler("test", config)
+	assert.NoError(t, th.wait(ctx, throttleDemand{CommandClassWrite, 1}))
+	assert.ErrorIs(t, th.wait(ctx, throttleDemand{CommandClassRead, 5}, throttleDemand{CommandClassWrite, 1}), ErrThrottled)
+	assert.NoError(t, th.wait(ctx, throttleDemand{CommandClassRead, 10}))
+}
+
+func TestThrottlerRefundOnCancel(t *testing.T) {
+	config := DefaultThrottleConfig()
+	config.Rate = 10
+	config.Burst = 1
+	config.MaxWait = time.Second
+	th := newThrottler("test", config)
+	now := time.Now()
+	th.def.now = func() time.Time { return now }
+
+	assert.NoError(t, th.wait(context.Background(), throttleDemand{CommandClassRead, 1}))
+	// 等待期间 ctx 结束，退还令牌
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, th.wait(ctx, throttleDemand{CommandClassRead, 1}), context.Canceled)
+	wait, ok := th.def.reserve(1, time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, 100*time.Millisecond, wait)
+}
+
+func TestCommandClass(t *testing.T) {
+	assert.Equal(t, CommandClassRead, commandClass("get"))
+	assert.Equal(t, CommandClassWrite, commandClass("SET"))
+	assert.Equal(t, CommandClassScan, commandClass("scan"))
+	assert.Equal(t, CommandClassScript, commandClass("evalsha"))
+}
+
+func TestPriority(t *testing.T) {
+	assert.Equal(t, PriorityNormal, priorityFromContext(context.Background()))
+	assert.Equal(t, PriorityHigh, priorityFromContext(WithPriority(context.Background(), PriorityHigh)))
+}
@@ -0,0 +1,150 @@
+package eredis
+
+import (
+	"encoding"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// valueTransformer 对写入 redis 的值做可逆的转换，例如压缩、加密。
+// 写入时按配置顺序依次 encode，读取时按相反顺序 decode，decode 需要兼容未经过转换的旧值。
+// field 为 hash 的字段，string 类型的值为空字符串
+type valueTransformer interface {
+	name() string
+	encode(key, field string, data []byte) ([]byte, error)
+	decode(key, field string, data []byte) ([]byte, error)
+}
+
+// encodeRaw 转换写入的值，无法转换为字节的值保持不变，由 go-redis 处理
+func (r *Component) encodeRaw(key, field string, value interface{}) (interface{}, error) {
+	if len(r.transformers) == 0 {
+		return value, nil
+	}
+	data, ok := valueBytes(value)
+	if !ok {
+		return value, nil
+	}
+	var err error
+	for _, t := range r.transformers {
+		if data, err = t.encode(key, field, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// decodeRaw 还原读取的值，失败时返回 *DecodeError
+func (r *Component) decodeRaw(key, field, value string) (string, error) {
+	if len(r.transformers) == 0 {
+		return value, nil
+	}
+	data := []byte(value)
+	var err error
+	for i := len(r.transformers) - 1; i >= 0; i-- {
+		if data, err = r.transformers[i].decode(key, field, data); err != nil {
+			return "", &DecodeError{Key: key, Field: field, Codec: r.transformers[i].name(), Err: err}
+		}
+	}
+	return string(data), nil
+}
+
+// decodeReply 还原 MGET、HMGET 等命令返回的单个值，nil 表示不存在
+func (r *Component) decodeReply(key, field string, reply interface{}) (interface{}, error) {
+	s, ok := reply.(string)
+	if !ok {
+		return reply, nil
+	}
+	return r.decodeRaw(key, field, s)
+}
+
+// encodeHash 转换 hash 的所有值
+func (r *Component) encodeHash(key string, hash map[string]interface{}) (map[string]interface{}, error) {
+	if len(r.transformers) == 0 {
+		return hash, nil
+	}
+	ret := make(map[string]interface{}, len(hash))
+	for field, value := range hash {
+		v, err := r.encodeRaw(key, field, value)
+		if err != nil {
+			return nil, err
+		}
+		ret[field] = v
+	}
+	return ret, nil
+}
+
+// valueBytes 按 go-redis 的规则将值格式化为字节
+func valueBytes(value interface{}) ([]byte, bool) {
+	switch v := value.(type) {
+	case nil:
+		return []byte{}, true
+	case string:
+		return []byte(v), true
+	case []byte:
+		return v, true
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10), true
+	case int8:
+		return strconv.AppendInt(nil, int64(v), 10), true
+	case int16:
+		return strconv.AppendInt(nil, int64(v), 10), true
+	case int32:
+		return strconv.AppendInt(nil, int64(v), 10), true
+	case int64:
+		return strconv.AppendInt(nil, v, 10), true
+	case uint:
+		return strconv.AppendUint(nil, uint64(v), 10), true
+	case uint8:
+		return strconv.AppendUint(nil, uint64(v), 10), true
+	case uint16:
+		return strconv.AppendUint(nil, uint64(v), 10), true
+	case uint32:
+		return strconv.AppendUint(nil, uint64(v), 10), true
+	case uint64:
+		return strconv.AppendUint(nil, v, 10), true
+	case float32:
+		return strconv.AppendFloat(nil, float64(v), 'f', -1, 64), true
+	case float64:
+		return strconv.AppendFloat(nil, v, 'f', -1, 64), true
+	case bool:
+		if v {
+			return []byte("1"), true
+		}
+		return []byte("0"), true
+	case time.Time:
+		return v.AppendFormat(nil, time.RFC3339Nano), true
+	case time.Duration:
+		return strconv.AppendInt(nil, v.Nanoseconds(), 10), true
+	case encoding.BinaryMarshaler:
+		data, err := v.MarshalBinary()
+		return data, err == nil
+	}
+	return nil, false
+}
+
+// stringValue 还原 GET、HGET 等命令的返回值
+func (r *Component) stringValue(cmd *redis.StringCmd, key, field string) (string, error) {
+	if cmd.Err() != nil {
+		return cmd.Val(), r.cmdErr(cmd)
+	}
+	value, err := r.decodeRaw(key, field, cmd.Val())
+	return value, r.wrapErr(cmd.Name(), err)
+}
+
+// encodeValues 转换 MSET 的所有值
+func (r *Component) encodeValues(values map[string]interface{}) (map[string]interface{}, error) {
+	if len(r.transformers) == 0 {
+		return values, nil
+	}
+	ret := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		v, err := r.encodeRaw(key, "", value)
+		if err != nil {
+			return nil, err
+		}
+		ret[key] = v
+	}
+	return ret, nil
+}
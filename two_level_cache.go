@@ -0,0 +1,307 @@
+package eredis
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/redis/go-redis/v9"
+)
+
+// 本地缓存失效方式
+const (
+	// InvalidationPubSub 写入方通过 pub/sub 广播失效的 key，只能感知经过 TwoLevelCache 的写入
+	InvalidationPubSub = "pubsub"
+	// InvalidationTracking 使用 redis 6 的 CLIENT TRACKING BCAST 模式，由 redis 推送任意客户端修改的 key，
+	// 只支持 stub、sentinel 模式，cluster 模式下退化为 pubsub
+	InvalidationTracking = "tracking"
+)
+
+const (
+	l1ChannelPrefix   = "eredis:l1:invalidate:"
+	trackingChannel   = "__redis__:invalidate"
+	trackingKeepalive = time.Second
+)
+
+// TwoLevelOption 二级缓存选项
+type TwoLevelOption func(c *TwoLevelCache)
+
+// WithL1Size 设置本地缓存的最大条数，默认 10000
+func WithL1Size(size int) TwoLevelOption {
+	return func(c *TwoLevelCache) {
+		c.size = size
+	}
+}
+
+// WithL1TTL 设置本地缓存的过期时间，失效消息丢失时最多读到该时间的旧值，默认 1min
+func WithL1TTL(ttl time.Duration) TwoLevelOption {
+	return func(c *TwoLevelCache) {
+		c.ttl = ttl
+	}
+}
+
+// WithL1Policy 设置本地缓存的淘汰策略 lru|lfu，默认 lru
+func WithL1Policy(policy string) TwoLevelOption {
+	return func(c *TwoLevelCache) {
+		c.policy = policy
+	}
+}
+
+// WithInvalidation 设置失效方式 pubsub|tracking，默认 pubsub
+func WithInvalidation(mode string) TwoLevelOption {
+	return func(c *TwoLevelCache) {
+		c.mode = mode
+	}
+}
+
+// WithInvalidationChannel 设置 pubsub 失效方式使用的 channel，默认 eredis:l1:invalidate:{组件名}
+func WithInvalidationChannel(channel string) TwoLevelOption {
+	return func(c *TwoLevelCache) {
+		c.channel = channel
+	}
+}
+
+// WithTrackingPrefixes 设置 tracking 失效方式关注的 key 前缀，默认关注所有 key
+func WithTrackingPrefixes(prefixes ...string) TwoLevelOption {
+	return func(c *TwoLevelCache) {
+		c.prefixes = prefixes
+	}
+}
+
+// TwoLevelCache 进程内 L1 + redis L2 的二级缓存，读请求优先读本地缓存，写请求写入 redis 后通知所有实例删除本地缓存。
+// 订阅连接断开重连期间可能丢失失效消息，因此每次重连都会清空本地缓存。
+type TwoLevelCache struct {
+	comp     *Component
+	l1       *localCache
+	size     int
+	ttl      time.Duration
+	policy   string
+	mode     string
+	channel  string
+	prefixes []string
+
+	sub     redis.UniversalClient
+	pubsub  *redis.PubSub
+	tracker *redis.Client
+	subID   int64 // subID 订阅连接的 CLIENT ID，tracking 模式下失效消息重定向到该连接
+	tracked int64 // tracked 已经开启 tracking 的订阅连接 ID，0 表示未开启，此时不写入本地缓存
+	cancel  context.CancelFunc
+}
+
+// NewTwoLevelCache 创建二级缓存，使用完毕后需要调用 Close
+func (r *Component) NewTwoLevelCache(opts ...TwoLevelOption) *TwoLevelCache {
+	c := &TwoLevelCache{
+		comp:    r,
+		size:    10000,
+		ttl:     time.Minute,
+		policy:  LocalCacheLRU,
+		mode:    InvalidationPubSub,
+		channel: l1ChannelPrefix + r.name,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.mode == InvalidationTracking && r.Cluster() != nil {
+		r.logger.Warn("client tracking is not supported in cluster mode, fallback to pubsub invalidation", elog.FieldName(r.name))
+		c.mode = InvalidationPubSub
+	}
+	c.l1 = newLocalCache(c.policy, c.size, c.ttl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.sub = r.newSubscriber(func(ctx context.Context, cn *redis.Conn) error {
+		// 重连期间的失效消息已经丢失，清空本地缓存
+		c.l1.purge()
+		if c.mode != InvalidationTracking {
+			return nil
+		}
+		id, err := cn.ClientID(ctx).Result()
+		if err != nil {
+			return err
+		}
+		atomic.StoreInt64(&c.subID, id)
+		return nil
+	})
+	if c.mode == InvalidationTracking {
+		c.pubsub = c.sub.Subscribe(ctx, trackingChannel)
+		opt := *r.Stub().Options()
+		opt.PoolSize, opt.MinIdleConns = 1, 0
+		opt.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+			atomic.StoreInt64(&c.tracked, 0)
+			return nil
+		}
+		c.tracker = redis.NewClient(&opt)
+		go c.track(ctx)
+	} else {
+		c.pubsub = c.sub.Subscribe(ctx, c.channel)
+	}
+	go c.listen()
+	return c
+}
+
+// Get 读取 key，本地缓存未命中时读取 redis 并回填本地缓存
+func (c *TwoLevelCache) Get(ctx context.Context, key string) (string, error) {
+	if value, ok := c.l1.get(key); ok {
+		c.metric("hit")
+		return value, nil
+	}
+	c.metric("miss")
+
+	epoch := c.l1.currentEpoch()
+	value, err := c.comp.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if c.mode != InvalidationTracking || atomic.LoadInt64(&c.tracked) != 0 {
+		if c.l1.set(key, value, epoch) {
+			c.metric("evict")
+		}
+	}
+	return value, nil
+}
+
+// Set 写入 redis 并通知所有实例删除本地缓存
+func (c *TwoLevelCache) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	err := c.comp.Set(ctx, key, value, expire)
+	c.l1.del(key)
+	if err != nil {
+		return err
+	}
+	return c.Invalidate(ctx, key)
+}
+
+// Del 删除 redis 中的 key 并通知所有实例删除本地缓存
+func (c *TwoLevelCache) Del(ctx context.Context, keys ...string) (int64, error) {
+	n, err := c.comp.del(ctx, keys)
+	c.l1.del(keys...)
+	if err != nil {
+		return n, c.comp.wrapErr("del", err)
+	}
+	return n, c.Invalidate(ctx, keys...)
+}
+
+// Invalidate 通知所有实例删除本地缓存，绕过 TwoLevelCache 直接修改 redis 后调用；tracking 模式下由 redis 推送，无需调用
+func (c *TwoLevelCache) Invalidate(ctx context.Context, keys ...string) error {
+	c.l1.del(keys...)
+	if c.mode == InvalidationTracking || len(keys) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(keys)
+	if err != nil {
+		return c.comp.wrapErr("publish", err)
+	}
+	cmd := c.comp.client.Publish(ctx, c.channel, payload)
+	return c.comp.cmdErr(cmd)
+}
+
+// Purge 清空本实例的本地缓存
+func (c *TwoLevelCache) Purge() {
+	c.l1.purge()
+}
+
+// Close 关闭订阅连接
+func (c *TwoLevelCache) Close() error {
+	c.cancel()
+	_ = c.pubsub.Close()
+	if c.tracker != nil {
+		_ = c.tracker.Close()
+	}
+	return c.comp.wrapErr("close", c.sub.Close())
+}
+
+// listen 处理失效消息
+func (c *TwoLevelCache) listen() {
+	for msg := range c.pubsub.Channel() {
+		var keys []string
+		switch {
+		case c.mode == InvalidationTracking:
+			// redis 推送的是带全局前缀的 key
+			for _, key := range msg.PayloadSlice {
+				if key, ok := c.comp.stripKeyPrefix(key); ok {
+					keys = append(keys, key)
+				}
+			}
+			if len(msg.PayloadSlice) > 0 && len(keys) == 0 {
+				continue
+			}
+		default:
+			if err := json.Unmarshal([]byte(msg.Payload), &keys); err != nil {
+				c.comp.logger.Warn("invalid l1 invalidation message", elog.FieldErr(err), elog.FieldValue(msg.Payload))
+				continue
+			}
+		}
+		c.metric("invalidate")
+		if len(keys) == 0 {
+			// FLUSHDB、FLUSHALL 时 redis 推送空的 key 列表
+			c.l1.purge()
+			continue
+		}
+		c.l1.del(keys...)
+	}
+}
+
+// track 在专用连接上开启 tracking 并把失效消息重定向到订阅连接，订阅连接或者 tracking 连接重连后重新开启
+func (c *TwoLevelCache) track(ctx context.Context) {
+	ticker := time.NewTicker(trackingKeepalive)
+	defer ticker.Stop()
+
+	conn := c.tracker.Conn()
+	defer func() {
+		_ = conn.Close()
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		id := atomic.LoadInt64(&c.subID)
+		if id == 0 || id == atomic.LoadInt64(&c.tracked) {
+			if err := conn.Ping(ctx).Err(); err != nil && ctx.Err() == nil {
+				c.comp.logger.Warn("client tracking keepalive fail", elog.FieldErr(err))
+				_ = conn.Close()
+				conn = c.tracker.Conn()
+				atomic.StoreInt64(&c.tracked, 0)
+			}
+			continue
+		}
+
+		args := []interface{}{"client", "tracking", "on", "redirect", id, "bcast"}
+		for _, prefix := range c.prefixes {
+			args = append(args, "prefix", c.comp.config.KeyPrefix+prefix)
+		}
+		if len(c.prefixes) == 0 && c.comp.config.KeyPrefix != "" {
+			args = append(args, "prefix", c.comp.config.KeyPrefix)
+		}
+		_ = conn.Do(ctx, "client", "tracking", "off").Err()
+		if err := conn.Do(ctx, args...).Err(); err != nil {
+			c.comp.logger.Warn("client tracking fail", elog.FieldErr(err))
+			continue
+		}
+		// 开启 tracking 之前的修改不会推送，清空本地缓存
+		c.l1.purge()
+		atomic.StoreInt64(&c.tracked, id)
+	}
+}
+
+func (c *TwoLevelCache) metric(code string) {
+	emetric.CacheHandleCounter.Inc(emetric.TypeRedis, c.comp.name, "l1", code)
+}
+
+// newSubscriber 创建专用于订阅的客户端，不经过组件的拦截器，onConnect 在每次建立连接时调用
+func (r *Component) newSubscriber(onConnect func(ctx context.Context, cn *redis.Conn) error) redis.UniversalClient {
+	if c := r.Cluster(); c != nil {
+		opt := *c.Options()
+		opt.OnConnect = onConnect
+		return redis.NewClusterClient(&opt)
+	}
+	opt := *r.Stub().Options()
+	// tracking 重定向的失效消息只能以 RESP2 pub/sub 消息的形式推送
+	opt.Protocol = 2
+	opt.OnConnect = onConnect
+	return redis.NewClient(&opt)
+}
@@ -0,0 +1,220 @@
+package eredis
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestTwoLevelCache 创建二级缓存，测试结束后关闭
+func newTestTwoLevelCache(t *testing.T, comp *Component, opts ...TwoLevelOption) *TwoLevelCache {
+	c := comp.NewTwoLevelCache(opts...)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// waitSubscribers 等待 channel 的订阅者达到 n 个，避免订阅生效前发布的失效消息丢失
+func waitSubscribers(t *testing.T, comp *Component, channel string, n int64) {
+	assert.Eventually(t, func() bool {
+		res, err := comp.Stub().PubSubNumSub(context.Background(), channel).Result()
+		return err == nil && res[channel] >= n
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+// assertL1Cached 读取 key 并确认本地缓存已回填
+func assertL1Cached(t *testing.T, c *TwoLevelCache, key, want string) {
+	value, err := c.Get(context.Background(), key)
+	assert.NoError(t, err)
+	assert.Equal(t, want, value)
+	value, ok := c.l1.get(key)
+	assert.True(t, ok)
+	assert.Equal(t, want, value)
+}
+
+// assertL1Invalidated 等待 key 的本地缓存被失效消息删除
+func assertL1Invalidated(t *testing.T, c *TwoLevelCache, key string) {
+	assert.Eventually(t, func() bool {
+		_, ok := c.l1.get(key)
+		return !ok
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+func TestTwoLevelCachePubSub(t *testing.T) {
+	ctx := context.Background()
+	compA := newTestRedis(t, "twoLevelPubSubA")
+	compB := newTestRedis(t, "twoLevelPubSubB")
+	channel := newTestKey(t, compA, "l1")
+	a := newTestTwoLevelCache(t, compA, WithInvalidationChannel(channel))
+	b := newTestTwoLevelCache(t, compB, WithInvalidationChannel(channel))
+	waitSubscribers(t, compA, channel, 2)
+
+	key := newTestKey(t, compA, "l1")
+	assert.NoError(t, compA.Set(ctx, key, "v1", time.Minute))
+	assertL1Cached(t, a, key, "v1")
+
+	// 另一个实例写入后广播失效
+	assert.NoError(t, b.Set(ctx, key, "v2", time.Minute))
+	assertL1Invalidated(t, a, key)
+	assertL1Cached(t, a, key, "v2")
+
+	// 绕过二级缓存修改 redis 时需要手动广播
+	assert.NoError(t, compB.Set(ctx, key, "v3", time.Minute))
+	_, ok := a.l1.get(key)
+	assert.True(t, ok)
+	assert.NoError(t, b.Invalidate(ctx, key))
+	assertL1Invalidated(t, a, key)
+	assertL1Cached(t, a, key, "v3")
+
+	n, err := b.Del(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assertL1Invalidated(t, a, key)
+	_, err = a.Get(ctx, key)
+	assert.True(t, IsNil(err))
+}
+
+func TestTwoLevelCacheTracking(t *testing.T) {
+	ctx := context.Background()
+	compA := newTestRedis(t, "twoLevelTrackingA")
+	compB := newTestRedis(t, "twoLevelTrackingB")
+	if err := compA.Stub().Do(ctx, "client", "tracking", "off").Err(); err != nil {
+		t.Skipf("client tracking unsupported: %v", err)
+	}
+	a := newTestTwoLevelCache(t, compA, WithInvalidation(InvalidationTracking))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&a.tracked) != 0
+	}, 3*time.Second, 10*time.Millisecond)
+	assert.Equal(t, atomic.LoadInt64(&a.subID), atomic.LoadInt64(&a.tracked))
+
+	// 任意客户端的修改都由 redis 推送，无需调用 Invalidate
+	key := newTestKey(t, compA, "l1")
+	assert.NoError(t, compB.Set(ctx, key, "v1", time.Minute))
+	assertL1Cached(t, a, key, "v1")
+	assert.NoError(t, compB.Set(ctx, key, "v2", time.Minute))
+	assertL1Invalidated(t, a, key)
+	assertL1Cached(t, a, key, "v2")
+
+	_, err := compB.Del(ctx, key)
+	assert.NoError(t, err)
+	assertL1Invalidated(t, a, key)
+}
+
+// afterGetHook GET 命令返回后执行 fn，模拟读取 redis 期间收到失效消息
+type afterGetHook struct {
+	fn func()
+}
+
+func (h afterGetHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h afterGetHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		if cmd.Name() == "get" {
+			h.fn()
+		}
+		return err
+	}
+}
+
+func (h afterGetHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestTwoLevelCacheInvalidateDuringGet(t *testing.T) {
+	ctx := context.Background()
+	compA := newTestRedis(t, "twoLevelRaceA")
+	compB := newTestRedis(t, "twoLevelRaceB")
+	channel := newTestKey(t, compA, "l1")
+	a := newTestTwoLevelCache(t, compA, WithInvalidationChannel(channel))
+	b := newTestTwoLevelCache(t, compB, WithInvalidationChannel(channel))
+	waitSubscribers(t, compA, channel, 2)
+
+	key := newTestKey(t, compA, "l1")
+	assert.NoError(t, compA.Set(ctx, key, "v1", time.Minute))
+
+	var once sync.Once
+	compA.Stub().AddHook(afterGetHook{fn: func() {
+		once.Do(func() {
+			epoch := a.l1.currentEpoch()
+			assert.NoError(t, b.Invalidate(ctx, key))
+			assert.Eventually(t, func() bool {
+				return a.l1.currentEpoch() != epoch
+			}, 2*time.Second, 10*time.Millisecond)
+		})
+	}})
+
+	// 读取期间收到的失效消息使回填作废，避免把旧值写入本地缓存
+	value, err := a.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", value)
+	_, ok := a.l1.get(key)
+	assert.False(t, ok)
+
+	assertL1Cached(t, a, key, "v1")
+}
+
+func TestTwoLevelCacheReconnect(t *testing.T) {
+	ctx := context.Background()
+	comp := newTestRedis(t, "twoLevelReconnect")
+
+	var (
+		mu        sync.Mutex
+		recording bool
+		conns     []net.Conn
+	)
+	opt := comp.Stub().Options()
+	dial := opt.Dialer
+	opt.Dialer = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		cn, err := dial(ctx, network, addr)
+		mu.Lock()
+		if err == nil && recording {
+			conns = append(conns, cn)
+		}
+		mu.Unlock()
+		return cn, err
+	}
+	mu.Lock()
+	recording = true
+	mu.Unlock()
+	c := newTestTwoLevelCache(t, comp)
+	mu.Lock()
+	recording = false
+	subConns := conns
+	mu.Unlock()
+	assert.NotEmpty(t, subConns)
+	other := newTestTwoLevelCache(t, comp)
+	waitSubscribers(t, comp, c.channel, 2)
+
+	key := newTestKey(t, comp, "l1")
+	assert.NoError(t, comp.Set(ctx, key, "v1", time.Minute))
+	assertL1Cached(t, c, key, "v1")
+
+	// 订阅连接断开期间的失效消息会丢失，重连时清空本地缓存
+	for _, cn := range subConns {
+		_ = cn.Close()
+	}
+	assert.Eventually(t, func() bool {
+		return c.l1.len() == 0
+	}, 3*time.Second, 10*time.Millisecond)
+
+	// 重连后重新订阅，其他实例广播的失效消息仍然生效
+	assert.NoError(t, comp.Set(ctx, key, "v2", time.Minute))
+	assert.Eventually(t, func() bool {
+		if _, err := c.Get(ctx, key); err != nil {
+			return false
+		}
+		if err := other.Invalidate(ctx, key); err != nil {
+			return false
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, ok := c.l1.get(key)
+		return !ok
+	}, 3*time.Second, 10*time.Millisecond)
+	assertL1Cached(t, c, key, "v2")
+}
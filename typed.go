@@ -0,0 +1,133 @@
+package eredis
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// Codec 返回组件的编解码器
+func (r *Component) Codec() Codec {
+	return r.codec
+}
+
+// GetValue 读取 key，使用组件的编解码器解码为 T，T 可以是结构体或者结构体指针
+func GetValue[T any](ctx context.Context, r *Component, key string) (T, error) {
+	return getValue[T](ctx, r, r.codec, key)
+}
+
+// SetValue 使用组件的编解码器编码 value 后写入 key
+func SetValue(ctx context.Context, r *Component, key string, value interface{}, expire time.Duration) error {
+	return setValue(ctx, r, r.codec, key, value, expire)
+}
+
+// GetJSON 读取 key，按 JSON 解码为 T
+func GetJSON[T any](ctx context.Context, r *Component, key string) (T, error) {
+	return getValue[T](ctx, r, codecs[CodecJSON], key)
+}
+
+// SetJSON 按 JSON 编码 value 后写入 key
+func SetJSON(ctx context.Context, r *Component, key string, value interface{}, expire time.Duration) error {
+	return setValue(ctx, r, codecs[CodecJSON], key, value, expire)
+}
+
+// MGetValues 批量读取 key 并解码，不存在的 key 不会出现在结果中
+func MGetValues[T any](ctx context.Context, r *Component, keys ...string) (map[string]T, error) {
+	reply, err := r.mgetValues(ctx, keys)
+	if err != nil {
+		return nil, r.wrapErr("mget", err)
+	}
+	values := make(map[string]T, len(reply))
+	for i, v := range reply {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		value, err := decodeValue[T](r.codec, keys[i], "", []byte(s))
+		if err != nil {
+			return nil, r.wrapErr("mget", err)
+		}
+		values[keys[i]] = value
+	}
+	return values, nil
+}
+
+// HGetValue 读取 hash 的 field，使用组件的编解码器解码为 T
+func HGetValue[T any](ctx context.Context, r *Component, key string, field string) (T, error) {
+	var zero T
+	data, err := r.stringValue(r.client.HGet(ctx, key, field), key, field)
+	if err != nil {
+		return zero, err
+	}
+	value, err := decodeValue[T](r.codec, key, field, []byte(data))
+	return value, r.wrapErr("hget", err)
+}
+
+// HSetValue 使用组件的编解码器编码 value 后写入 hash 的 field
+func HSetValue(ctx context.Context, r *Component, key string, field string, value interface{}) error {
+	data, err := r.codec.Marshal(value)
+	if err != nil {
+		return r.wrapErr("hset", err)
+	}
+	return r.HSet(ctx, key, field, data)
+}
+
+// HGetAllInto 读取 hash 的所有 field，按结构体的 redis tag 赋值给 T，T 可以是结构体或者结构体指针，key 不存在时返回 Nil
+//
+//	type User struct {
+//		Name string `redis:"name"`
+//		Age  int    `redis:"age"`
+//	}
+func HGetAllInto[T any](ctx context.Context, r *Component, key string) (T, error) {
+	var zero T
+	cmd := r.hgetAll(ctx, key)
+	if cmd.Err() != nil {
+		return zero, r.cmdErr(cmd)
+	}
+	if len(cmd.Val()) == 0 {
+		return zero, r.wrapErr("hgetall", Nil)
+	}
+	value, target := newTarget[T]()
+	if err := cmd.Scan(target); err != nil {
+		return zero, r.wrapErr("hgetall", &DecodeError{Key: key, Codec: "hash", Err: err})
+	}
+	return *value, nil
+}
+
+func getValue[T any](ctx context.Context, r *Component, codec Codec, key string) (T, error) {
+	var zero T
+	data, err := r.stringValue(r.client.Get(ctx, key), key, "")
+	if err != nil {
+		return zero, err
+	}
+	value, err := decodeValue[T](codec, key, "", []byte(data))
+	return value, r.wrapErr("get", err)
+}
+
+func setValue(ctx context.Context, r *Component, codec Codec, key string, value interface{}, expire time.Duration) error {
+	data, err := codec.Marshal(value)
+	if err != nil {
+		return r.wrapErr("set", err)
+	}
+	return r.Set(ctx, key, data, expire)
+}
+
+// decodeValue 解码为 T，失败时返回记录了 key 的 *DecodeError
+func decodeValue[T any](codec Codec, key, field string, data []byte) (T, error) {
+	value, target := newTarget[T]()
+	if err := codec.Unmarshal(data, target); err != nil {
+		var zero T
+		return zero, &DecodeError{Key: key, Field: field, Codec: codec.Name(), Err: err}
+	}
+	return *value, nil
+}
+
+// newTarget 返回保存结果的指针以及解码目标，T 为指针类型时分配一个新对象，保证 proto.Message 等指针类型可以直接解码
+func newTarget[T any]() (*T, interface{}) {
+	value := new(T)
+	if rt := reflect.TypeOf(*value); rt != nil && rt.Kind() == reflect.Ptr {
+		*value = reflect.New(rt.Elem()).Interface().(T)
+		return value, *value
+	}
+	return value, value
+}
@@ -0,0 +1,122 @@
+package eredis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/ego/core/constant"
+	"github.com/gotomicro/ego/server"
+)
+
+// consumerServer 后台消费者共用的 ego server.Server 方法，scheme、address 用于 Info
+type consumerServer struct {
+	name    string
+	scheme  string
+	address string
+}
+
+// Name 配置名称
+func (s consumerServer) Name() string {
+	return s.name
+}
+
+// PackageName 包名
+func (s consumerServer) PackageName() string {
+	return PackageName
+}
+
+// Init 初始化
+func (s consumerServer) Init() error {
+	return nil
+}
+
+// Info 服务信息
+func (s consumerServer) Info() *server.ServiceInfo {
+	info := server.ApplyOptions(
+		server.WithScheme(s.scheme),
+		server.WithAddress(s.address),
+		server.WithKind(constant.ServiceConsumer),
+	)
+	return &info
+}
+
+// callHandler 调用 handler，panic 视为处理失败
+func callHandler(kind string, fn func() error) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("eredis: %s handler panic: %v", kind, rec)
+		}
+	}()
+	return fn()
+}
+
+// workerGroup 管理后台 worker 的生命周期：停止时不再拉取新任务，等待正在处理的任务完成，超时之后取消 ctx
+type workerGroup struct {
+	ctx      context.Context // ctx 处理任务使用，Stop 或者优雅退出超时时取消
+	cancel   context.CancelFunc
+	stopping chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func newWorkerGroup() workerGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	return workerGroup{ctx: ctx, cancel: cancel, stopping: make(chan struct{})}
+}
+
+// run 启动一个 worker
+func (g *workerGroup) run(fn func()) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn()
+	}()
+}
+
+// stopped 是否已经开始停止
+func (g *workerGroup) stopped() bool {
+	select {
+	case <-g.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep 等待 d，开始停止时立即返回 false
+func (g *workerGroup) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-g.stopping:
+		return false
+	}
+}
+
+// stop 立即停止，正在处理的任务的 ctx 被取消
+func (g *workerGroup) stop() {
+	g.stopOnce.Do(func() { close(g.stopping) })
+	g.cancel()
+	g.wg.Wait()
+}
+
+// gracefulStop 等待正在处理的任务完成，ctx 结束时取消正在处理的任务
+func (g *workerGroup) gracefulStop(ctx context.Context) {
+	g.stopOnce.Do(func() { close(g.stopping) })
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		g.cancel()
+		<-done
+	}
+	g.cancel()
+}
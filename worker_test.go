@@ -0,0 +1,17 @@
+package eredis
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallHandler(t *testing.T) {
+	assert.NoError(t, callHandler("job", func() error { return nil }))
+	boom := errors.New("boom")
+	assert.Equal(t, boom, callHandler("job", func() error { return boom }))
+	// handler panic 视为处理失败
+	err := callHandler("stream", func() error { panic("boom") })
+	assert.EqualError(t, err, "eredis: stream handler panic: boom")
+}